@@ -0,0 +1,261 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package asyncapi
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const brokerTimeout = 5 * time.Second
+
+var defaultPorts = map[string]string{
+	"kafka":        "9092",
+	"kafka-secure": "9093",
+	"amqp":         "5672",
+	"amqps":        "5671",
+	"mqtt":         "1883",
+	"secure-mqtt":  "8883",
+	"nats":         "4222",
+	"redis":        "6379",
+}
+
+// saslMechanisms maps AsyncAPI security scheme types to Kafka SASL mechanisms
+var saslMechanisms = map[string]string{
+	"plain":       "PLAIN",
+	"scramSha256": "SCRAM-SHA-256",
+	"scramSha512": "SCRAM-SHA-512",
+	"gssapi":      "GSSAPI",
+}
+
+// Broker is the address of a message broker, and how to check it.
+type Broker struct {
+	Address  string
+	Protocol string
+	TLS      bool
+
+	// SaslMechanism, if set, is checked to be enabled on a Kafka broker
+	SaslMechanism string
+}
+
+// BuildBroker determines the broker address from the server URL, which may
+// be of the form 'host:port' or 'scheme://host:port', and its protocol.
+// The SASL mechanism is taken from the server's security requirements.
+func BuildBroker(serverUrl string, protocol string, server *Server, spec *PartialModel) (*Broker, error) {
+	host := serverUrl
+	if strings.Contains(serverUrl, "://") {
+		parsed, err := url.Parse(serverUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker URL [%v]: %v", serverUrl, err)
+		}
+		if protocol == "" {
+			protocol = parsed.Scheme
+		}
+		host = parsed.Host
+	} else if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	protocol = strings.ToLower(protocol)
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port, found := defaultPorts[protocol]
+		if !found {
+			return nil, fmt.Errorf("no port in broker URL [%v] and no default for protocol [%v]", serverUrl, protocol)
+		}
+		host = net.JoinHostPort(host, port)
+	}
+
+	broker := &Broker{
+		Address:  host,
+		Protocol: protocol,
+		TLS:      protocol == "kafka-secure" || protocol == "amqps" || protocol == "secure-mqtt",
+	}
+	if server != nil && spec != nil {
+		for _, requirement := range server.Security {
+			for schemeName := range requirement {
+				if mechanism, found := saslMechanisms[spec.Components.SecuritySchemes[schemeName].Type]; found {
+					broker.SaslMechanism = mechanism
+				}
+			}
+		}
+	}
+	return broker, nil
+}
+
+// Check connects to the broker and, for Kafka and AMQP, performs a protocol
+// handshake to confirm that a broker of the expected kind is listening.
+func (b *Broker) Check() error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: brokerTimeout}
+	if b.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", b.Address, &tls.Config{})
+	} else {
+		conn, err = dialer.Dial("tcp", b.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to broker [%v]: %v", b.Address, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(brokerTimeout))
+
+	switch b.Protocol {
+	case "kafka", "kafka-secure":
+		if err := checkKafka(conn); err != nil {
+			return fmt.Errorf("kafka handshake with broker [%v] failed: %v", b.Address, err)
+		}
+		if b.SaslMechanism != "" {
+			if err := checkKafkaSasl(conn, b.SaslMechanism); err != nil {
+				return fmt.Errorf("kafka SASL handshake with broker [%v] failed: %v", b.Address, err)
+			}
+		}
+	case "amqp", "amqps":
+		if err := checkAmqp(conn); err != nil {
+			return fmt.Errorf("AMQP handshake with broker [%v] failed: %v", b.Address, err)
+		}
+	default:
+		logrus.Debugf("no handshake for protocol [%v] - checked connection only", b.Protocol)
+	}
+	return nil
+}
+
+const (
+	kafkaApiVersionsKey  = 18
+	kafkaSaslHandshake   = 17
+	kafkaClientId        = "opendeps"
+	kafkaCorrelationBase = 0x0de9
+)
+
+// checkKafka sends an ApiVersions (v0) request and checks the response.
+func checkKafka(conn net.Conn) error {
+	body, err := kafkaRequest(conn, kafkaApiVersionsKey, 0, kafkaCorrelationBase, nil)
+	if err != nil {
+		return err
+	}
+	if len(body) < 2 {
+		return fmt.Errorf("truncated ApiVersions response")
+	}
+	if errorCode := int16(binary.BigEndian.Uint16(body)); errorCode != 0 {
+		return fmt.Errorf("ApiVersions returned error code %d", errorCode)
+	}
+	return nil
+}
+
+// checkKafkaSasl sends a SaslHandshake (v1) request for the mechanism and
+// checks the broker has it enabled.
+func checkKafkaSasl(conn net.Conn, mechanism string) error {
+	body, err := kafkaRequest(conn, kafkaSaslHandshake, 1, kafkaCorrelationBase+1, kafkaString(mechanism))
+	if err != nil {
+		return err
+	}
+	if len(body) < 6 {
+		return fmt.Errorf("truncated SaslHandshake response")
+	}
+	errorCode := int16(binary.BigEndian.Uint16(body))
+	if errorCode == 0 {
+		return nil
+	}
+	var enabled []string
+	count := int(int32(binary.BigEndian.Uint32(body[2:])))
+	rest := body[6:]
+	for i := 0; i < count && len(rest) >= 2; i++ {
+		length := int(binary.BigEndian.Uint16(rest))
+		if len(rest) < 2+length {
+			break
+		}
+		enabled = append(enabled, string(rest[2:2+length]))
+		rest = rest[2+length:]
+	}
+	return fmt.Errorf("mechanism %v not enabled (error code %d); enabled mechanisms: %v", mechanism, errorCode, strings.Join(enabled, ", "))
+}
+
+// kafkaRequest writes a request with a v1 header and returns the body of the
+// response, after checking its correlation ID.
+func kafkaRequest(conn net.Conn, apiKey int16, apiVersion int16, correlationId int32, body []byte) ([]byte, error) {
+	var req bytes.Buffer
+	_ = binary.Write(&req, binary.BigEndian, apiKey)
+	_ = binary.Write(&req, binary.BigEndian, apiVersion)
+	_ = binary.Write(&req, binary.BigEndian, correlationId)
+	req.Write(kafkaString(kafkaClientId))
+	req.Write(body)
+
+	var frame bytes.Buffer
+	_ = binary.Write(&frame, binary.BigEndian, int32(req.Len()))
+	frame.Write(req.Bytes())
+	if _, err := conn.Write(frame.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var size int32
+	if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+		return nil, fmt.Errorf("no response: %v", err)
+	}
+	if size < 4 || size > 1<<20 {
+		return nil, fmt.Errorf("unexpected response size %d - not a Kafka broker?", size)
+	}
+	resp := make([]byte, size)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, fmt.Errorf("truncated response: %v", err)
+	}
+	if got := int32(binary.BigEndian.Uint32(resp)); got != correlationId {
+		return nil, fmt.Errorf("unexpected correlation ID %d - not a Kafka broker?", got)
+	}
+	return resp[4:], nil
+}
+
+func kafkaString(s string) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, int16(len(s)))
+	buf.WriteString(s)
+	return buf.Bytes()
+}
+
+var amqpProtocolHeader = []byte{'A', 'M', 'Q', 'P', 0, 0, 9, 1}
+
+// checkAmqp sends the AMQP 0-9-1 protocol header and checks the
+// broker responds with Connection.Start.
+func checkAmqp(conn net.Conn) error {
+	if _, err := conn.Write(amqpProtocolHeader); err != nil {
+		return err
+	}
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("no response: %v", err)
+	}
+	if bytes.HasPrefix(header, []byte("AMQP")) {
+		return fmt.Errorf("broker does not support AMQP 0-9-1")
+	}
+	if header[0] != 1 || binary.BigEndian.Uint16(header[1:]) != 0 {
+		return fmt.Errorf("unexpected frame - not an AMQP broker?")
+	}
+	method := make([]byte, 4)
+	if _, err := io.ReadFull(conn, method); err != nil {
+		return fmt.Errorf("truncated frame: %v", err)
+	}
+	if classId, methodId := binary.BigEndian.Uint16(method), binary.BigEndian.Uint16(method[2:]); classId != 10 || methodId != 10 {
+		return fmt.Errorf("expected Connection.Start, got method %d.%d", classId, methodId)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package asyncapi
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"strings"
+)
+
+// Handler handles AsyncAPI specs. They are not mocked.
+var Handler openapi.SpecHandler = asyncApiHandler{}
+
+type asyncApiHandler struct{}
+
+func (asyncApiHandler) Kind() string {
+	return "AsyncAPI"
+}
+
+func (asyncApiHandler) Detect(_ string, raw []byte) bool {
+	return IsAsyncApiSpec(raw)
+}
+
+func (asyncApiHandler) DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error) {
+	return DiscoverSpecs(dir, options)
+}
+
+// Scaffold returns a dependency using all of the channels in the spec.
+func (asyncApiHandler) Scaffold(_ string, raw []byte) (*model.Dependency, error) {
+	spec, err := ParseContent(raw)
+	if err != nil {
+		return nil, err
+	}
+	dep := &model.Dependency{
+		Summary:     spec.Info.Title,
+		Description: strings.TrimSpace(spec.Info.Description),
+		Version:     spec.Info.Version,
+		Channels:    spec.ChannelNames(),
+	}
+	if contact := spec.Info.Contact; contact != nil && (contact.Name != "" || contact.Email != "" || contact.Url != "") {
+		dep.Contact = &model.Contact{Name: contact.Name, Email: contact.Email, Url: contact.Url}
+	}
+	return dep, nil
+}
+
+func (asyncApiHandler) FindExternalRefs(_ string, raw []byte) ([]string, error) {
+	return openapi.FindExternalRefs(raw)
+}
+
+// Check checks the availability of the message broker of the dependency,
+// then its deployed version.
+// The server is selected by the name or description of the environment
+// server, if any.
+func (asyncApiHandler) Check(dep openapi.DependencyContext) error {
+	spec, err := Parse(dep.SpecPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse spec [%v]: %v\n", dep.SpecPath, err)
+	}
+
+	serverName := ""
+	if dep.EnvServer != nil {
+		serverName = dep.EnvServer.Description
+	}
+	server, err := spec.FindServer(serverName)
+	if err != nil && (serverName != "" || dep.ServerUrl == "") {
+		return fmt.Errorf("failed to select server in spec [%v]: %v", dep.SpecPath, err)
+	}
+
+	var protocol string
+	if server != nil {
+		protocol = server.Protocol
+	}
+	serverUrl, err := determineBrokerUrl(dep, server)
+	if err != nil {
+		return err
+	}
+
+	broker, err := BuildBroker(serverUrl, protocol, server, spec)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("checking %v broker [%v] for %v", broker.Protocol, broker.Address, dep.Name)
+	if err := broker.Check(); err != nil {
+		return err
+	}
+	return openapi.CheckDeployedVersion(dep, nil)
+}
+
+// determineBrokerUrl returns the URL of the broker from the server URL
+// override or the availability URL or, failing those, that of the
+// selected server in the spec.
+func determineBrokerUrl(dep openapi.DependencyContext, server *Server) (string, error) {
+	if serverUrl, found := dep.ServerUrlOverride(); found {
+		return serverUrl, nil
+	}
+	return openapi.ResolveServerUrl(server.ToOpenApiServer(), dep.Lookup)
+}
+
+// Validate checks that the channels used by the dependency are defined
+// in its spec, and that the spec version satisfies its version constraint.
+func (asyncApiHandler) Validate(dep openapi.DependencyContext) []openapi.Problem {
+	spec, err := Parse(dep.SpecPath)
+	if err != nil {
+		return []openapi.Problem{openapi.Problemf("", "spec could not be read: %v", err)}
+	}
+	logrus.Debugf("dependency [%v] spec is AsyncAPI %v", dep.Name, spec.AsyncApi)
+
+	var problems []openapi.Problem
+	if len(dep.Dependency.Channels) > 0 && len(spec.Servers) == 0 {
+		problems = append(problems, openapi.Problemf("", "spec has no servers - its broker cannot be tested"))
+	}
+	for i, channel := range dep.Dependency.Channels {
+		if !spec.HasChannel(channel) {
+			problems = append(problems, openapi.Problemf(fmt.Sprintf("/x-channels/%d", i), "channel [%v] is not defined in its spec", channel))
+		}
+	}
+	return append(problems, openapi.ValidateVersion(dep, spec.Info.Version, nil)...)
+}
+
+func (asyncApiHandler) StartMock(deps []openapi.DependencyContext, _ openapi.MockOptions) (openapi.Mock, error) {
+	for _, dep := range deps {
+		logrus.Warnf("skipping mock for %v - AsyncAPI specs are not mocked: %v", dep.Name, dep.SpecPath)
+	}
+	return nil, nil
+}
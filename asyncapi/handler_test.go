@@ -0,0 +1,77 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package asyncapi
+
+import (
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		dependency model.Dependency
+		want       []openapi.Problem
+	}{
+		{
+			name:       "channels defined",
+			spec:       "asyncapi: 2.6.0\ninfo: {title: Events, version: 1.0.0}\nservers:\n  prod: {url: 'kafka:9092', protocol: kafka}\nchannels:\n  orders: {}\n",
+			dependency: model.Dependency{Channels: []string{"orders"}},
+		},
+		{
+			name:       "channel missing from spec",
+			spec:       "asyncapi: 2.6.0\ninfo: {title: Events, version: 1.0.0}\nservers:\n  prod: {url: 'kafka:9092', protocol: kafka}\nchannels:\n  orders: {}\n",
+			dependency: model.Dependency{Channels: []string{"orders", "payments"}},
+			want: []openapi.Problem{
+				{Pointer: "/x-channels/1", Message: "channel [payments] is not defined in its spec"},
+			},
+		},
+		{
+			name:       "no servers",
+			spec:       "asyncapi: 2.6.0\ninfo: {title: Events, version: 1.0.0}\nchannels:\n  orders: {}\n",
+			dependency: model.Dependency{Channels: []string{"orders"}},
+			want: []openapi.Problem{
+				{Pointer: "", Message: "spec has no servers - its broker cannot be tested"},
+			},
+		},
+		{
+			name:       "version not satisfied",
+			spec:       "asyncapi: 2.6.0\ninfo: {title: Events, version: 1.0.0}\nchannels:\n  orders: {}\n",
+			dependency: model.Dependency{Version: "^2"},
+			want: []openapi.Problem{
+				{Pointer: "/version", Message: "spec version [1.0.0] does not satisfy version constraint [^2]"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specPath := filepath.Join(t.TempDir(), "events.yaml")
+			if err := ioutil.WriteFile(specPath, []byte(tt.spec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			dep := openapi.DependencyContext{Name: "events", Dependency: tt.dependency, SpecPath: specPath}
+			if got := Handler.Validate(dep); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package asyncapi
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/openapi"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ServerVariable struct {
+	Default     string
+	Enum        []string
+	Description string
+}
+
+// Server is a message broker. AsyncAPI 2 specs give its url; AsyncAPI 3
+// specs give its host and pathname instead.
+type Server struct {
+	Name        string `yaml:"-"`
+	Url         string
+	Host        string
+	Pathname    string
+	Protocol    string
+	Description string
+	Variables   map[string]ServerVariable
+	Security    []map[string][]string
+}
+
+type Channel struct {
+	Address string
+}
+
+type SecurityScheme struct {
+	Type string
+}
+
+type Components struct {
+	SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes"`
+}
+
+type PartialModel struct {
+	AsyncApi   string `yaml:"asyncapi"`
+	Info       openapi.Info
+	Servers    []Server `yaml:"-"`
+	Channels   map[string]Channel
+	Components Components
+}
+
+func Parse(specFile string) (*PartialModel, error) {
+	raw, err := fileutil.ReadAllContent(specFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseContent(raw)
+}
+
+// ParseContent parses the raw content of an AsyncAPI spec. Servers
+// are returned in the order they appear in the spec.
+func ParseContent(raw []byte) (*PartialModel, error) {
+	o := PartialModel{}
+	if err := yaml.Unmarshal(raw, &o); err != nil {
+		return nil, fmt.Errorf("error: %v\n", err)
+	}
+
+	var servers struct {
+		Servers yaml.MapSlice
+	}
+	if err := yaml.Unmarshal(raw, &servers); err != nil {
+		return nil, fmt.Errorf("error: %v\n", err)
+	}
+	for _, item := range servers.Servers {
+		content, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		server := Server{}
+		if err := yaml.Unmarshal(content, &server); err != nil {
+			return nil, fmt.Errorf("error parsing server [%v]: %v", item.Key, err)
+		}
+		server.Name = fmt.Sprint(item.Key)
+		if server.Url == "" && server.Host != "" {
+			server.Url = server.Host + server.Pathname
+		}
+		o.Servers = append(o.Servers, server)
+	}
+
+	logrus.Tracef("asyncapi parsed:\n%v\n\n", o)
+	return &o, nil
+}
+
+// IsAsyncApiSpec determines whether the raw content is an AsyncAPI spec.
+func IsAsyncApiSpec(raw []byte) bool {
+	var doc struct {
+		AsyncApi string `yaml:"asyncapi"`
+	}
+	return yaml.Unmarshal(raw, &doc) == nil && doc.AsyncApi != ""
+}
+
+// ChannelNames returns the names of the spec's channels, in lexical order.
+// For AsyncAPI 3 specs, whose channels have an address distinct from their
+// key, the address is returned.
+func (o *PartialModel) ChannelNames() []string {
+	var names []string
+	for key, channel := range o.Channels {
+		if channel.Address != "" {
+			names = append(names, channel.Address)
+		} else {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasChannel determines whether the spec defines the channel,
+// by key or by address.
+func (o *PartialModel) HasChannel(name string) bool {
+	for key, channel := range o.Channels {
+		if key == name || channel.Address == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FindServer returns the server with the given name or description,
+// or the first server if name is empty.
+func (o *PartialModel) FindServer(name string) (*Server, error) {
+	if len(o.Servers) == 0 {
+		return nil, fmt.Errorf("no servers found in spec")
+	}
+	if name == "" {
+		return &o.Servers[0], nil
+	}
+	for i, server := range o.Servers {
+		if server.Name == name || strings.EqualFold(server.Description, name) {
+			return &o.Servers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no server named [%v] found in spec", name)
+}
+
+// ToOpenApiServer adapts the server so that its URL template can be
+// resolved in the same way as that of an OpenAPI server.
+func (s *Server) ToOpenApiServer() openapi.Server {
+	variables := make(map[string]openapi.ServerVariable)
+	for name, v := range s.Variables {
+		variables[name] = openapi.ServerVariable{Default: v.Default, Enum: v.Enum, Description: v.Description}
+	}
+	return openapi.Server{Url: s.Url, Description: s.Description, Variables: variables}
+}
+
+// DiscoverSpecs returns the paths of the AsyncAPI specs in dir
+// that satisfy the options.
+func DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error) {
+	files, err := fileutil.FindFiles(dir, []string{".yaml", ".yml", ".json"}, options)
+	if err != nil {
+		return nil, err
+	}
+	var specs []string
+	for _, file := range files {
+		specPath := filepath.Join(dir, filepath.FromSlash(file))
+		raw, err := fileutil.ReadAllContent(specPath)
+		if err != nil {
+			return nil, err
+		}
+		if IsAsyncApiSpec(raw) {
+			specs = append(specs, specPath)
+		}
+	}
+	return specs, nil
+}
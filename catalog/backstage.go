@@ -0,0 +1,228 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io"
+	"opendeps.org/opendeps/fileutil"
+	"strings"
+)
+
+const defaultNamespace = "default"
+
+type Metadata struct {
+	Name        string
+	Namespace   string
+	Title       string
+	Description string
+}
+
+type EntitySpec struct {
+	Type         string
+	ConsumesApis []string `yaml:"consumesApis"`
+	ProvidesApis []string `yaml:"providesApis"`
+	Definition   interface{}
+	Target       string
+	Targets      []string
+}
+
+// Entity is a Backstage catalog entity, such as a Component or an API.
+type Entity struct {
+	Kind     string
+	Metadata Metadata
+	Spec     EntitySpec
+
+	// Source is the location of the file declaring the entity
+	Source string `yaml:"-"`
+}
+
+// Api is the definition of an API entity.
+type Api struct {
+	Name        string
+	Title       string
+	Description string
+	Type        string
+
+	// Location of the definition, if it is held in another file
+	Location string
+
+	// Content of the definition, if it is held inline
+	Content []byte
+}
+
+// Catalog holds the entities of a catalog file, and those of the
+// files it references through Location entities.
+type Catalog struct {
+	// Entities declared in the root catalog file
+	Entities []Entity
+
+	// all entities, keyed by reference
+	all map[string]Entity
+}
+
+// Load reads the catalog file, such as catalog-info.yaml, at the given
+// path or URL, following the targets of any Location entities.
+func Load(location string) (*Catalog, error) {
+	c := &Catalog{all: make(map[string]Entity)}
+	entities, err := c.load(location, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	c.Entities = entities
+	return c, nil
+}
+
+func (c *Catalog) load(location string, visited map[string]bool) ([]Entity, error) {
+	if visited[location] {
+		return nil, nil
+	}
+	visited[location] = true
+
+	raw, err := fileutil.ReadAllContent(location)
+	if err != nil {
+		return nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	var entities []Entity
+	for {
+		entity := Entity{}
+		if err := decoder.Decode(&entity); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error parsing catalog file [%v]: %v", location, err)
+		}
+		if entity.Kind == "" {
+			continue
+		}
+		entity.Source = location
+		if entity.Metadata.Namespace == "" {
+			entity.Metadata.Namespace = defaultNamespace
+		}
+		entities = append(entities, entity)
+		c.all[entity.ref()] = entity
+
+		if strings.EqualFold(entity.Kind, "Location") {
+			for _, target := range append([]string{entity.Spec.Target}, entity.Spec.Targets...) {
+				if target == "" {
+					continue
+				}
+				targetLocation, err := fileutil.ResolveLocation(location, target)
+				if err != nil {
+					return nil, err
+				}
+				logrus.Debugf("following catalog location: %v", targetLocation)
+				if _, err := c.load(targetLocation, visited); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return entities, nil
+}
+
+func (e Entity) ref() string {
+	return strings.ToLower(e.Kind) + ":" + e.Metadata.Namespace + "/" + e.Metadata.Name
+}
+
+// ParseRef returns the canonical form of an entity reference such as
+// 'petstore', 'default/petstore' or 'api:default/petstore', using the
+// given kind and namespace if the reference does not include them.
+func ParseRef(ref string, kind string, namespace string) string {
+	if i := strings.Index(ref, ":"); i >= 0 {
+		kind, ref = ref[:i], ref[i+1:]
+	}
+	if i := strings.Index(ref, "/"); i >= 0 {
+		namespace, ref = ref[:i], ref[i+1:]
+	}
+	return strings.ToLower(kind) + ":" + namespace + "/" + ref
+}
+
+// ConsumedApis returns the references of the APIs consumed by the Components
+// in the root catalog file, other than those they provide themselves.
+func (c *Catalog) ConsumedApis() []string {
+	provided := make(map[string]bool)
+	for _, entity := range c.Components() {
+		for _, ref := range entity.Spec.ProvidesApis {
+			provided[ParseRef(ref, "api", entity.Metadata.Namespace)] = true
+		}
+	}
+	var consumed []string
+	seen := make(map[string]bool)
+	for _, entity := range c.Components() {
+		for _, ref := range entity.Spec.ConsumesApis {
+			apiRef := ParseRef(ref, "api", entity.Metadata.Namespace)
+			if !provided[apiRef] && !seen[apiRef] {
+				seen[apiRef] = true
+				consumed = append(consumed, apiRef)
+			}
+		}
+	}
+	return consumed
+}
+
+// Components returns the Components in the root catalog file.
+func (c *Catalog) Components() []Entity {
+	var components []Entity
+	for _, entity := range c.Entities {
+		if strings.EqualFold(entity.Kind, "Component") {
+			components = append(components, entity)
+		}
+	}
+	return components
+}
+
+// GetApi returns the API entity with the given canonical reference.
+func (c *Catalog) GetApi(ref string) (*Api, error) {
+	entity, found := c.all[ref]
+	if !found {
+		return nil, fmt.Errorf("API [%v] is not defined in the catalog", ref)
+	}
+
+	api := &Api{
+		Name:        entity.Metadata.Name,
+		Title:       entity.Metadata.Title,
+		Description: entity.Metadata.Description,
+		Type:        entity.Spec.Type,
+	}
+	switch definition := entity.Spec.Definition.(type) {
+	case string:
+		api.Content = []byte(definition)
+	case map[interface{}]interface{}:
+		for _, substitution := range []string{"$text", "$yaml", "$json"} {
+			if target, ok := definition[substitution].(string); ok {
+				location, err := fileutil.ResolveLocation(entity.Source, target)
+				if err != nil {
+					return nil, err
+				}
+				api.Location = location
+				return api, nil
+			}
+		}
+		content, err := yaml.Marshal(definition)
+		if err != nil {
+			return nil, err
+		}
+		api.Content = content
+	default:
+		return nil, fmt.Errorf("API [%v] has no definition", ref)
+	}
+	return api, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/editor"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"opendeps.org/opendeps/spechandlers"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var flagManifestFile, flagAddSpec, flagAddSummary, flagAddVersion, flagAddAvailabilityPath, flagAddAvailabilityUrl string
+var flagAddRequired bool
+
+// addCmd represents the add command
+var addCmd = &cobra.Command{
+	Use:   "add DEPENDENCY",
+	Short: "Add a dependency to an OpenDeps manifest",
+	Long: `Adds a dependency to an existing OpenDeps manifest, preserving
+its comments and the order of its keys.
+
+The summary, version and availability path are suggested from
+the dependency's OpenAPI specification, unless set by flags.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		depName := args[0]
+		manifestPath, e := loadManifestEditor()
+		if e.HasDependency(depName) {
+			logrus.Fatalf("dependency [%v] already exists in manifest: %v", depName, manifestPath)
+		}
+
+		dep, _, availability := buildDependency(manifestPath, flagAddSpec, flagAddRequired)
+		if flagAddSummary != "" {
+			dep.Summary = flagAddSummary
+		}
+		if flagAddVersion != "" {
+			dep.Version = flagAddVersion
+		}
+		if flagAddAvailabilityPath != "" || flagAddAvailabilityUrl != "" {
+			dep.Availability = &model.Availability{
+				Path: flagAddAvailabilityPath,
+				Url:  flagAddAvailabilityUrl,
+			}
+			availability = nil
+		}
+
+		if err := e.SetDependency(depName, dep); err != nil {
+			logrus.Fatal(err)
+		}
+		annotateAvailability(e, depName, availability)
+		if err := e.Save(manifestPath); err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("added dependency [%v] to manifest: %v", depName, manifestPath)
+	},
+}
+
+// removeCmd represents the remove command
+var removeCmd = &cobra.Command{
+	Use:   "remove DEPENDENCY",
+	Short: "Remove a dependency from an OpenDeps manifest",
+	Long: `Removes a dependency from an existing OpenDeps manifest, preserving
+its comments and the order of its keys.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		depName := args[0]
+		manifestPath, e := loadManifestEditor()
+		if !e.RemoveDependency(depName) {
+			logrus.Fatalf("dependency [%v] not found in manifest: %v", depName, manifestPath)
+		}
+		if err := e.Save(manifestPath); err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("removed dependency [%v] from manifest: %v", depName, manifestPath)
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{addCmd, removeCmd} {
+		cmd.Flags().StringVarP(&flagManifestFile, "manifest", "m", "", "Path to the OpenDeps manifest, or its directory (default is the working directory)")
+	}
+	addCmd.Flags().StringVar(&flagAddSpec, "spec", "", "Path or URL of the dependency's OpenAPI specification")
+	addCmd.Flags().StringVar(&flagAddSummary, "summary", "", "Summary of the dependency (default is the title of the specification)")
+	addCmd.Flags().StringVar(&flagAddVersion, "version", "", "Version of the dependency (default is the version of the specification)")
+	addCmd.Flags().StringVar(&flagAddAvailabilityPath, "availability-path", "", "Path of the dependency's availability endpoint, relative to its server")
+	addCmd.Flags().StringVar(&flagAddAvailabilityUrl, "availability-url", "", "Fully qualified URL of the dependency's availability endpoint")
+	addCmd.Flags().BoolVarP(&flagAddRequired, "required", "r", false, "Mark the dependency as required")
+	addAvailabilityHintsFlag(addCmd)
+	_ = addCmd.MarkFlagRequired("spec")
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(removeCmd)
+}
+
+func loadManifestEditor() (string, *editor.Editor) {
+	var args []string
+	if flagManifestFile != "" {
+		args = []string{flagManifestFile}
+	}
+	manifestPath, err := discovery.FindManifestFile(args)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	e, err := editor.Load(manifestPath)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return manifestPath, e
+}
+
+// buildDependency creates a dependency for the spec, suggesting its summary,
+// description, contact and version from the spec, if it can be parsed, using
+// the handler for its kind. For OpenAPI specs, the availability path is also
+// inferred, and the parsed spec and inferred availability are returned.
+func buildDependency(manifestPath string, spec string, required bool) (model.Dependency, *openapi.PartialModel, *inferredAvailability) {
+	if !fileutil.IsRemote(spec) && !filepath.IsAbs(spec) && !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+		spec = "./" + filepath.ToSlash(spec)
+	}
+	dep := model.Dependency{
+		Spec:     spec,
+		Required: required,
+	}
+
+	specNormalisedPath := fileutil.MakeAbsoluteRelativeToFile(spec, manifestPath)
+	raw, err := fileutil.ReadAllContent(specNormalisedPath)
+	if err != nil {
+		logrus.Warnf("unable to read spec [%v] - details must be provided manually: %v", specNormalisedPath, err)
+		return dep, nil, nil
+	}
+	handler := spechandlers.DetectForContent(specNormalisedPath, raw)
+	scaffolded, err := handler.Scaffold(specNormalisedPath, raw)
+	if err != nil {
+		logrus.Warnf("unable to read %v spec [%v] - details must be provided manually: %v", handler.Kind(), specNormalisedPath, err)
+		return dep, nil, nil
+	}
+	scaffolded.Spec = dep.Spec
+	scaffolded.Required = dep.Required
+	dep = *scaffolded
+	if handler != openapi.Handler {
+		return dep, nil, nil
+	}
+
+	openapiSpec, err := openapi.ParseContent(raw)
+	if err != nil {
+		logrus.Warnf("unable to read spec [%v] - details must be provided manually: %v", specNormalisedPath, err)
+		return dep, nil, nil
+	}
+	availability := determineAvailabilityPath(openapiSpec)
+	dep.Availability = &model.Availability{
+		Path: availability.Path,
+	}
+	return dep, openapiSpec, &availability
+}
+
+var invalidDependencyNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// suggestDependencyName derives a name for the dependency from the
+// file name of its spec or, if that is generic, its summary.
+func suggestDependencyName(spec string, dep model.Dependency) string {
+	base := path.Base(strings.SplitN(filepath.ToSlash(spec), "?", 2)[0])
+	name := strings.TrimSuffix(base, path.Ext(base))
+	switch strings.ToLower(name) {
+	case "openapi", "swagger", "asyncapi", "schema", "spec", "api", "":
+		if dep.Summary != "" {
+			name = dep.Summary
+		}
+	}
+	return strings.Trim(invalidDependencyNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
@@ -0,0 +1,220 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/manifest/editor"
+	"opendeps.org/opendeps/openapi"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// flagAvailabilityHints are checked before the built-in availability path hints
+var flagAvailabilityHints []string
+
+var healthTagPattern = regexp.MustCompile(`(?i)^(health|healthcheck|health-check|monitoring|status|probes?)$`)
+var healthDescriptionPattern = regexp.MustCompile(`(?i)\b(health|healthcheck|liveness|readiness|heartbeat|ping)\b`)
+
+type availabilityConfidence string
+
+const (
+	confidenceHigh   availabilityConfidence = "high"
+	confidenceMedium availabilityConfidence = "medium"
+	confidenceLow    availabilityConfidence = "low"
+)
+
+// inferredAvailability is an availability path suggested for a spec,
+// with how confident the suggestion is and why it was made.
+type inferredAvailability struct {
+	Path       string
+	Confidence availabilityConfidence
+	Reason     string
+}
+
+func addAvailabilityHintsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&flagAvailabilityHints, "availability-hints", nil, "Additional availability paths to look for, checked before the built-in hints (e.g. /internal/health)")
+}
+
+func getAvailabilityPathHints() []string {
+	return []string{
+		"/healthz",
+		"/health",
+		"/actuator/health",
+		"/livez",
+		"/readyz",
+		"/ready",
+		"/ping",
+		"/system/status",
+		"/status",
+	}
+}
+
+// determineAvailabilityPath suggests the path of the spec's availability
+// endpoint. Only GET operations that require neither parameters nor
+// security are considered. In order of preference, it chooses a path
+// matching a configured hint, then a built-in hint, then a path ending
+// with a built-in hint, then an operation tagged or described as a health
+// check, then the only candidate operation, falling back to '/'.
+func determineAvailabilityPath(spec *openapi.PartialModel) inferredAvailability {
+	var candidates []string
+	for path, pathItem := range spec.Paths {
+		operation, found := pathItem["get"].(map[interface{}]interface{})
+		if !found {
+			continue
+		}
+		if requiresParameters(path, pathItem, operation) || requiresSecurity(operation, spec.Security) {
+			logrus.Tracef("skipping availability path candidate requiring parameters or security: %v", path)
+			continue
+		}
+		candidates = append(candidates, path)
+	}
+	sort.Strings(candidates)
+
+	if len(candidates) == 0 {
+		return inferredAvailability{"/", confidenceLow, "no GET operation without required parameters or security"}
+	}
+	for _, hint := range flagAvailabilityHints {
+		if path, found := findCandidate(candidates, func(path string) bool { return path == hint }); found {
+			return inferredAvailability{path, confidenceHigh, "matches configured hint"}
+		}
+	}
+	for _, hint := range getAvailabilityPathHints() {
+		if path, found := findCandidate(candidates, func(path string) bool { return path == hint }); found {
+			return inferredAvailability{path, confidenceHigh, "matches common health check path"}
+		}
+	}
+	for _, hint := range getAvailabilityPathHints() {
+		if path, found := findCandidate(candidates, func(path string) bool { return strings.HasSuffix(path, hint) }); found {
+			return inferredAvailability{path, confidenceMedium, "ends with common health check path " + hint}
+		}
+	}
+	if path, found := findCandidate(candidates, func(path string) bool {
+		return isHealthCheckOperation(spec.Paths[path]["get"].(map[interface{}]interface{}))
+	}); found {
+		return inferredAvailability{path, confidenceMedium, "operation is tagged or described as a health check"}
+	}
+	if len(candidates) == 1 {
+		return inferredAvailability{candidates[0], confidenceLow, "only GET operation without required parameters or security"}
+	}
+	return inferredAvailability{"/", confidenceLow, "no health check operation found"}
+}
+
+// note describes the confidence in the inferred path, asking
+// for low confidence suggestions to be checked by hand.
+func (a inferredAvailability) note() string {
+	note := fmt.Sprintf("inferred with %v confidence: %v", a.Confidence, a.Reason)
+	if a.Confidence == confidenceLow {
+		note += " - check it manually"
+	}
+	return note
+}
+
+// annotateAvailability reports the inferred availability path of the
+// dependency, if any, and writes the confidence note as a comment on the
+// path in the manifest.
+func annotateAvailability(e *editor.Editor, depName string, availability *inferredAvailability) {
+	if availability == nil {
+		return
+	}
+	if availability.Confidence == confidenceLow {
+		logrus.Warnf("availability path for [%v]: %v (%v)", depName, availability.Path, availability.note())
+	} else {
+		logrus.Infof("availability path for [%v]: %v (%v)", depName, availability.Path, availability.note())
+	}
+	e.SetAvailabilityPathComment(depName, availability.note())
+}
+
+// annotateAvailabilities annotates the inferred availability
+// paths of the dependencies, in order of their names.
+func annotateAvailabilities(e *editor.Editor, availabilities map[string]*inferredAvailability) {
+	var depNames []string
+	for depName := range availabilities {
+		depNames = append(depNames, depName)
+	}
+	sort.Strings(depNames)
+	for _, depName := range depNames {
+		annotateAvailability(e, depName, availabilities[depName])
+	}
+}
+
+func findCandidate(candidates []string, matches func(path string) bool) (string, bool) {
+	for _, candidate := range candidates {
+		if matches(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// requiresParameters determines whether calling the operation requires
+// path parameters, or other parameters marked as required.
+func requiresParameters(path string, pathItem map[string]interface{}, operation map[interface{}]interface{}) bool {
+	if strings.Contains(path, "{") {
+		return true
+	}
+	pathParams, _ := pathItem["parameters"].([]interface{})
+	operationParams, _ := operation["parameters"].([]interface{})
+	for _, p := range append(pathParams, operationParams...) {
+		param, ok := p.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		if required, _ := param["required"].(bool); required || param["in"] == "path" {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresSecurity determines whether the operation, or the spec if the
+// operation does not override it, requires a security scheme. A security
+// requirement list containing an empty requirement makes security optional.
+func requiresSecurity(operation map[interface{}]interface{}, globalSecurity []map[string][]string) bool {
+	if security, found := operation["security"].([]interface{}); found {
+		for _, requirement := range security {
+			if r, ok := requirement.(map[interface{}]interface{}); !ok || len(r) == 0 {
+				return false
+			}
+		}
+		return len(security) > 0
+	}
+	for _, requirement := range globalSecurity {
+		if len(requirement) == 0 {
+			return false
+		}
+	}
+	return len(globalSecurity) > 0
+}
+
+func isHealthCheckOperation(operation map[interface{}]interface{}) bool {
+	tags, _ := operation["tags"].([]interface{})
+	for _, tag := range tags {
+		if t, ok := tag.(string); ok && healthTagPattern.MatchString(t) {
+			return true
+		}
+	}
+	for _, field := range []string{"summary", "operationId", "description"} {
+		if text, ok := operation[field].(string); ok && healthDescriptionPattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/fileutil"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of remote specs and schemas",
+	Long: `Lists or clears the local cache of specs and schemas
+fetched from remote URLs.
+
+Cached content is revalidated using ETag and Last-Modified
+headers once its TTL has passed. Use the --offline flag on
+any command to serve content only from the cache.`,
+}
+
+// cacheLsCmd represents the cache ls command
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached content",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := fileutil.ListCacheEntries()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if len(entries) == 0 {
+			logrus.Info("cache is empty")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "URL\tSIZE\tFETCHED\tSTATUS")
+		for _, entry := range entries {
+			status := "fresh"
+			if time.Since(entry.FetchedAt) >= flagCacheTtl {
+				status = "stale"
+			}
+			fmt.Fprintf(w, "%v\t%d\t%v\t%v\n", entry.Url, entry.Size, entry.FetchedAt.Format(time.RFC3339), status)
+		}
+		w.Flush()
+	},
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached content",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		removed, err := fileutil.ClearCache()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("removed %d cache entries", removed)
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/discovery"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const envPrefix = "OPENDEPS"
+
+// flags that are not read from config files or environment variables
+var unboundFlags = map[string]bool{
+	"config": true,
+	"help":   true,
+}
+
+// configFiles holds the paths of the config files that were read, in
+// increasing order of precedence
+var configFiles []string
+
+func getProjectConfigFilenames() []string {
+	return []string{
+		".opendeps.yaml",
+		".opendeps.yml",
+	}
+}
+
+// loadProjectConfig merges the project config file adjacent to the manifest,
+// if any, over the user config file. The manifest is located in the same way
+// as commands do; if there is none, the directory given in args, or the
+// working directory, is searched.
+func loadProjectConfig(args []string) {
+	projectDir := findProjectDir(args)
+	for _, filename := range getProjectConfigFilenames() {
+		configPath := filepath.Join(projectDir, filename)
+		if _, err := os.Stat(configPath); err != nil {
+			continue
+		}
+		if absUserConfig, _ := filepath.Abs(viper.ConfigFileUsed()); absUserConfig == configPath {
+			return
+		}
+
+		projectConfig := viper.New()
+		projectConfig.SetConfigFile(configPath)
+		if err := projectConfig.ReadInConfig(); err != nil {
+			logrus.Fatalf("error reading project config file: %v: %v", configPath, err)
+		}
+		if err := viper.MergeConfigMap(projectConfig.AllSettings()); err != nil {
+			logrus.Fatalf("error merging project config file: %v: %v", configPath, err)
+		}
+		configFiles = append(configFiles, configPath)
+		fmt.Fprintln(os.Stderr, "Using project config file:", configPath)
+		return
+	}
+}
+
+func findProjectDir(args []string) string {
+	if manifestPath, err := discovery.FindManifestFile(args); err == nil {
+		return filepath.Dir(manifestPath)
+	}
+	if len(args) > 0 {
+		if absPath, err := filepath.Abs(args[0]); err == nil {
+			if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+				return absPath
+			}
+		}
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// applyConfigToFlags sets each flag of the command that was not given on the
+// command line from the environment variable or config files, if set there.
+func applyConfigToFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || unboundFlags[flag.Name] || !viper.IsSet(flag.Name) {
+			return
+		}
+		setting := viper.Get(flag.Name)
+		if isConfigMap(setting) {
+			// read maps from the config files, as viper lowercases their keys
+			readConfigSection(flag.Name, &setting)
+		}
+		value := formatFlagValue(setting)
+		if err := cmd.Flags().Set(flag.Name, value); err != nil {
+			logrus.Fatalf("invalid value for %v from config: %v: %v", flag.Name, value, err)
+		}
+		logrus.Tracef("set flag %v from config: %v", flag.Name, value)
+	})
+}
+
+func isConfigMap(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// formatFlagValue converts a value read from a config file to the
+// textual form accepted by flags.
+func formatFlagValue(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var pairs []string
+		for key, val := range v {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", key, val))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case map[interface{}]interface{}:
+		var pairs []string
+		for key, val := range v {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", key, val))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// readConfigSection unmarshals the section of the config files with the given
+// key into out, merging the sections of later files over earlier ones. YAML
+// and JSON config files are read directly, rather than through viper, as
+// viper lowercases keys, which would break names such as those of
+// dependencies. Sections of config files in other formats are read through viper.
+func readConfigSection(key string, out interface{}) bool {
+	var merged interface{}
+	for _, configFile := range configFiles {
+		var section interface{}
+		switch strings.ToLower(filepath.Ext(configFile)) {
+		case ".yaml", ".yml", ".json":
+			section = readRawConfigSection(configFile, key)
+		default:
+			section = viper.Get(key)
+		}
+		if section != nil {
+			merged = mergeConfigValues(merged, section)
+		}
+	}
+	if merged == nil {
+		return false
+	}
+	marshalled, err := yaml.Marshal(merged)
+	if err != nil {
+		logrus.Fatalf("invalid %v in config file: %v", key, err)
+	}
+	if err := yaml.Unmarshal(marshalled, out); err != nil {
+		logrus.Fatalf("invalid %v in config file: %v", key, err)
+	}
+	return true
+}
+
+// readRawConfigSection returns the value of the top level key of the config file, if set.
+func readRawConfigSection(configFile string, key string) interface{} {
+	raw, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		logrus.Fatalf("error reading config file: %v: %v", configFile, err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		logrus.Fatalf("error reading config file: %v: %v", configFile, err)
+	}
+	return doc[key]
+}
+
+// mergeConfigValues merges the entries of the override map into the base map,
+// recursively. Values other than maps replace those in base.
+func mergeConfigValues(base interface{}, override interface{}) interface{} {
+	baseMap, baseIsMap := base.(map[interface{}]interface{})
+	overrideMap, overrideIsMap := override.(map[interface{}]interface{})
+	if !baseIsMap || !overrideIsMap {
+		return override
+	}
+	merged := make(map[interface{}]interface{})
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overrideMap {
+		merged[key] = mergeConfigValues(merged[key], value)
+	}
+	return merged
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyConfigToFlags(t *testing.T) {
+	tests := []struct {
+		name          string
+		userConfig    string
+		projectConfig string
+		want          map[string]string
+	}{
+		{
+			name:       "preserves case of map keys",
+			userConfig: "server:\n  MyDep: https://example.com\n  other_dep: https://other.example.com\n",
+			want:       map[string]string{"MyDep": "https://example.com", "other_dep": "https://other.example.com"},
+		},
+		{
+			name:          "merges project config over user config",
+			userConfig:    "server:\n  MyDep: https://user.example.com\n  UserDep: https://user.example.com\n",
+			projectConfig: "server:\n  MyDep: https://project.example.com\n",
+			want:          map[string]string{"MyDep": "https://project.example.com", "UserDep": "https://user.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			viper.Reset()
+			defer viper.Reset()
+			defer func() { configFiles = nil }()
+
+			userConfig := filepath.Join(dir, "user.yaml")
+			writeFile(t, userConfig, tt.userConfig)
+			viper.SetConfigFile(userConfig)
+			if err := viper.ReadInConfig(); err != nil {
+				t.Fatal(err)
+			}
+			configFiles = []string{userConfig}
+
+			if tt.projectConfig != "" {
+				projectDir := filepath.Join(dir, "project")
+				writeFile(t, filepath.Join(projectDir, ".opendeps.yaml"), tt.projectConfig)
+				loadProjectConfig([]string{projectDir})
+			}
+
+			var servers map[string]string
+			cmd := &cobra.Command{}
+			cmd.Flags().StringToStringVar(&servers, "server", nil, "")
+			applyConfigToFlags(cmd)
+
+			if !reflect.DeepEqual(servers, tt.want) {
+				t.Errorf("server flag = %v, want %v", servers, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFlagValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"string", "foo", "foo"},
+		{"bool", true, "true"},
+		{"list", []interface{}{"a", "b"}, "a,b"},
+		{"map", map[interface{}]interface{}{"MyDep": "x", "a": 1}, "MyDep=x,a=1"},
+		{"string map", map[string]interface{}{"b": "y", "a": "x"}, "a=x,b=y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatFlagValue(tt.value); got != tt.want {
+				t.Errorf("formatFlagValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"os"
+	"strings"
+)
+
+var flagEnvironment string
+var flagServerVars map[string]string
+
+const serverVarEnvPrefix = "OPENDEPS_SERVER_VAR_"
+
+// addServerVarFlag registers the flag used to set server variables
+// on commands that resolve server URLs.
+func addServerVarFlag(cmd *cobra.Command) {
+	cmd.Flags().StringToStringVar(&flagServerVars, "server-var", nil, "Set a server URL variable for all dependencies (e.g. region=eu) or one dependency (e.g. foo_service.region=eu)")
+}
+
+// resolveEnvironment returns the environment selected by flag, combining its
+// definition in the manifest with that in the config file, if any. The config
+// file takes precedence for dependencies defined in both. If the environment
+// is only used to select an overlay file, nil is returned.
+func resolveEnvironment(manifestPath string, manifest *model.OpenDeps) *model.Environment {
+	if flagEnvironment == "" {
+		return nil
+	}
+
+	environment := &model.Environment{
+		Servers: make(map[string]model.EnvironmentServer),
+	}
+	found := false
+	if manifestEnv, ok := manifest.Environments[flagEnvironment]; ok {
+		for depName, server := range manifestEnv.Servers {
+			environment.Servers[depName] = server
+		}
+		found = true
+	}
+	if configEnv, ok := loadConfigEnvironments()[flagEnvironment]; ok {
+		for depName, server := range configEnv.Servers {
+			environment.Servers[depName] = server
+		}
+		found = true
+	}
+	if !found {
+		if _, hasOverlay := model.GetEnvironmentOverlayPath(manifestPath, flagEnvironment); hasOverlay {
+			// the environment only overlays the manifest
+			return nil
+		}
+		logrus.Fatalf("environment [%v] is not defined in the manifest, config file or an overlay", flagEnvironment)
+	}
+	logrus.Debugf("using environment [%v] with %d server(s)", flagEnvironment, len(environment.Servers))
+	return environment
+}
+
+// loadConfigEnvironments reads the environments defined in the config files.
+func loadConfigEnvironments() map[string]model.Environment {
+	var environments map[string]model.Environment
+	readConfigSection("environments", &environments)
+	return environments
+}
+
+// buildDependencyContext returns the dependency with the location of its
+// spec and the server settings that apply to it. The server URL override is
+// taken from the --server flag, if set, otherwise the environment.
+func buildDependencyContext(manifestPath string, depName string, dep model.Dependency, environment *model.Environment) openapi.DependencyContext {
+	depContext := openapi.DependencyContext{
+		ManifestPath: manifestPath,
+		Name:         depName,
+		Dependency:   dep,
+		SpecPath:     fileutil.MakeAbsoluteRelativeToFile(dep.Spec, manifestPath),
+		EnvServer:    findEnvironmentServer(depName, environment),
+		Lookup:       buildVariableLookup(depName, environment),
+	}
+	if serverUrl, found := flagServers[depName]; found {
+		logrus.Debugf("determined server [%v] from overrides", serverUrl)
+		depContext.ServerUrl = serverUrl
+	} else if depContext.EnvServer != nil && depContext.EnvServer.Url != "" {
+		logrus.Debugf("determined server [%v] from environment [%v]", depContext.EnvServer.Url, flagEnvironment)
+		depContext.ServerUrl = depContext.EnvServer.Url
+	}
+	return depContext
+}
+
+// findEnvironmentServer returns the server of the dependency in the environment, if any.
+func findEnvironmentServer(depName string, environment *model.Environment) *model.EnvironmentServer {
+	if environment != nil {
+		if server, found := environment.Servers[depName]; found {
+			return &server
+		}
+	}
+	return nil
+}
+
+// buildVariableLookup returns the values of server variables for a dependency.
+// In order of precedence, values are taken from the dependency-specific flag,
+// the flag for all dependencies, the environment variable named
+// OPENDEPS_SERVER_VAR_<NAME>, then the selected environment, if any.
+func buildVariableLookup(depName string, environment *model.Environment) openapi.VariableLookup {
+	return func(name string) (string, bool) {
+		if value, found := flagServerVars[depName+"."+name]; found {
+			return value, true
+		} else if value, found := flagServerVars[name]; found {
+			return value, true
+		} else if value, found := os.LookupEnv(serverVarEnvPrefix + strings.ToUpper(name)); found {
+			return value, true
+		} else if environment != nil {
+			if value, found := environment.Servers[depName].Variables[name]; found {
+				return value, true
+			}
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"gatehill.io/imposter/fileutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"opendeps.org/opendeps/manifest/editor"
+	"opendeps.org/opendeps/manifest/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var flagInitTitle, flagInitVersion string
+var flagInitContactName, flagInitContactEmail, flagInitContactUrl string
+var flagInitDependencies map[string]string
+var flagInitRequired []string
+var flagNonInteractive bool
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init DIR",
+	Short: "Create a new OpenDeps manifest",
+	Long: `Creates a new OpenDeps manifest in a directory.
+
+When run in a terminal, you are prompted for the manifest's
+details and dependencies. Otherwise, or if --non-interactive
+is set, they are taken from flags.
+
+If DIR is not specified, the current working directory is used.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var dir string
+		if len(args) == 0 {
+			dir = "."
+		} else {
+			dir = args[0]
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		manifestPath := fileutil.GenerateFilePathAdjacentToFile(filepath.Join(dir, "opendeps"), ".yaml", flagForceOverwrite)
+
+		var manifest *model.OpenDeps
+		var availabilities map[string]*inferredAvailability
+		if flagNonInteractive || !isTerminal(os.Stdin) {
+			manifest, availabilities = buildManifestFromFlags(dir, manifestPath)
+		} else {
+			manifest, availabilities = promptForManifest(bufio.NewReader(os.Stdin), os.Stdout, dir, manifestPath)
+		}
+
+		e, err := editor.New(manifest)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		annotateAvailabilities(e, availabilities)
+		if err := e.Save(manifestPath); err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("wrote OpenDeps manifest file with %d dependencies: %v", len(manifest.Dependencies), manifestPath)
+	},
+}
+
+func init() {
+	initCmd.Flags().StringVar(&flagInitTitle, "title", "", "Title of the manifest")
+	initCmd.Flags().StringVar(&flagInitVersion, "version", "1.0.0", "Version of the manifest")
+	initCmd.Flags().StringVar(&flagInitContactName, "contact-name", "", "Name of the contact for the manifest")
+	initCmd.Flags().StringVar(&flagInitContactEmail, "contact-email", "", "Email address of the contact for the manifest")
+	initCmd.Flags().StringVar(&flagInitContactUrl, "contact-url", "", "URL of the contact for the manifest")
+	initCmd.Flags().StringToStringVarP(&flagInitDependencies, "dependency", "d", nil, "Dependency name and spec path or URL (e.g. foo_service=https://example.com/openapi.yaml)")
+	initCmd.Flags().StringSliceVarP(&flagInitRequired, "required", "r", nil, "Name of a dependency to mark as required")
+	initCmd.Flags().BoolVarP(&flagNonInteractive, "non-interactive", "y", false, "Do not prompt; take all values from flags")
+	initCmd.Flags().BoolVarP(&flagForceOverwrite, "force-overwrite", "f", false, "Force overwrite of destination file(s) if already exist")
+	addAvailabilityHintsFlag(initCmd)
+	rootCmd.AddCommand(initCmd)
+}
+
+func newManifest(dir string, title string, version string) *model.OpenDeps {
+	if title == "" {
+		title = "OpenDeps manifest for " + filepath.Base(dir)
+	}
+	return &model.OpenDeps{
+		OpenDeps: model.OpenDepsSchemaVersion,
+		Info: &model.Info{
+			Title:   title,
+			Version: version,
+		},
+		Dependencies: make(map[string]model.Dependency),
+	}
+}
+
+func buildContact(name string, email string, url string) *model.Contact {
+	if name == "" && email == "" && url == "" {
+		return nil
+	}
+	return &model.Contact{Name: name, Email: email, Url: url}
+}
+
+// buildManifestFromFlags builds the manifest from the flags, returning it
+// with the inferred availability paths of its dependencies.
+func buildManifestFromFlags(dir string, manifestPath string) (*model.OpenDeps, map[string]*inferredAvailability) {
+	manifest := newManifest(dir, flagInitTitle, flagInitVersion)
+	manifest.Info.Contact = buildContact(flagInitContactName, flagInitContactEmail, flagInitContactUrl)
+
+	required := make(map[string]bool)
+	for _, depName := range flagInitRequired {
+		if _, found := flagInitDependencies[depName]; !found {
+			logrus.Fatalf("required dependency [%v] is not one of the dependencies", depName)
+		}
+		required[depName] = true
+	}
+	availabilities := make(map[string]*inferredAvailability)
+	for depName, spec := range flagInitDependencies {
+		manifest.Dependencies[depName], _, availabilities[depName] = buildDependency(manifestPath, spec, required[depName])
+	}
+	return manifest, availabilities
+}
+
+// promptForManifest builds the manifest from the answers to prompts, returning
+// it with the inferred availability paths of its dependencies that were accepted.
+func promptForManifest(in *bufio.Reader, out io.Writer, dir string, manifestPath string) (*model.OpenDeps, map[string]*inferredAvailability) {
+	manifest := newManifest(dir,
+		prompt(in, out, "Title", newManifest(dir, flagInitTitle, "").Info.Title),
+		prompt(in, out, "Version", flagInitVersion),
+	)
+	manifest.Info.Contact = buildContact(
+		prompt(in, out, "Contact name (optional)", flagInitContactName),
+		prompt(in, out, "Contact email (optional)", flagInitContactEmail),
+		prompt(in, out, "Contact URL (optional)", flagInitContactUrl),
+	)
+
+	var depNames []string
+	for depName := range flagInitDependencies {
+		depNames = append(depNames, depName)
+	}
+	sort.Strings(depNames)
+	availabilities := make(map[string]*inferredAvailability)
+	for _, depName := range depNames {
+		manifest.Dependencies[depName], _, availabilities[depName] = buildDependency(manifestPath, flagInitDependencies[depName], contains(flagInitRequired, depName))
+	}
+
+	for {
+		spec := prompt(in, out, "Dependency spec path or URL (blank to finish)", "")
+		if spec == "" {
+			break
+		}
+		dep, _, availability := buildDependency(manifestPath, spec, false)
+		depName := prompt(in, out, "  Name", suggestDependencyName(spec, dep))
+		dep.Summary = prompt(in, out, "  Summary", dep.Summary)
+		dep.Required = promptYesNo(in, out, "  Required", false)
+		path := ""
+		if dep.Availability != nil {
+			path = dep.Availability.Path
+		}
+		if path = prompt(in, out, "  Availability path", path); path != "" {
+			dep.Availability = &model.Availability{Path: path}
+		} else {
+			dep.Availability = nil
+		}
+		if availability != nil && availability.Path == path {
+			availabilities[depName] = availability
+		} else {
+			delete(availabilities, depName)
+		}
+		manifest.Dependencies[depName] = dep
+	}
+	return manifest, availabilities
+}
+
+// prompt asks a question, returning the answer, or the default if none is given.
+func prompt(in *bufio.Reader, out io.Writer, question string, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Fprintf(out, "%v [%v]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(out, "%v: ", question)
+	}
+	answer, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		logrus.Fatal(err)
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return defaultValue
+	}
+	return answer
+}
+
+func promptYesNo(in *bufio.Reader, out io.Writer, question string, defaultValue bool) bool {
+	options := "y/N"
+	if defaultValue {
+		options = "Y/n"
+	}
+	for {
+		answer := strings.ToLower(prompt(in, out, question+" ("+options+")", ""))
+		switch answer {
+		case "":
+			return defaultValue
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/lint"
+	"opendeps.org/opendeps/manifest/severity"
+	"os"
+	"strings"
+)
+
+var flagLintFormat, flagLintFailOn string
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint OPENDEPS_FILE",
+	Short: "Check a manifest against policy rules",
+	Long: `Checks an OpenDeps manifest against built-in policy rules, such as
+requiring an availability path for required dependencies, beyond
+validity against the schema.
+
+Rules can be disabled, or their severity changed, in the config file.
+Findings can be suppressed with comments in the manifest.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestPath, err := discovery.FindManifestFile(args)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Debugf("linting opendeps manifest: %v", manifestPath)
+		failOn := parseLintFailOn()
+
+		result, err := lint.Lint(manifestPath, loadLintConfig())
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		switch strings.ToLower(flagLintFormat) {
+		case "text":
+			printLintFindings(result)
+		case "sarif":
+			wd, _ := os.Getwd()
+			sarif, err := result.Sarif(wd)
+			if err != nil {
+				logrus.Fatalf("error writing SARIF: %v", err)
+			}
+			fmt.Println(string(sarif))
+		default:
+			logrus.Fatalf("unsupported format [%v] - must be one of: text, sarif", flagLintFormat)
+		}
+
+		if failOn != "" && lintFailed(result, failOn) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVar(&flagLintFormat, "format", "text", "Format of the findings: text or sarif")
+	lintCmd.Flags().StringVar(&flagLintFailOn, "fail-on", string(severity.Error), "Exit with non-zero status if there are findings of this severity or higher: error, warning, note or none")
+}
+
+// loadLintConfig reads the rule settings from the 'lint' section of the config files.
+func loadLintConfig() lint.Config {
+	var config lint.Config
+	readConfigSection("lint", &config)
+	return config
+}
+
+func printLintFindings(result *lint.Result) {
+	findings := result.Unsuppressed()
+	for _, finding := range findings {
+		fmt.Printf("%v:%d:%d: %v: %v [%v]\n", result.ManifestPath, finding.Line, finding.Column, finding.Severity, finding.Message, finding.RuleId)
+	}
+	if suppressed := len(result.Findings) - len(findings); suppressed > 0 {
+		logrus.Infof("%d problem(s) found, %d suppressed", len(findings), suppressed)
+	} else {
+		logrus.Infof("%d problem(s) found", len(findings))
+	}
+}
+
+// parseLintFailOn returns the --fail-on severity, or an empty
+// severity if lint should never fail.
+func parseLintFailOn() severity.Severity {
+	if strings.EqualFold(flagLintFailOn, "none") {
+		return ""
+	}
+	failOn, err := severity.Parse(flagLintFailOn)
+	if err != nil {
+		logrus.Fatalf("invalid --fail-on: %v", err)
+	}
+	return failOn
+}
+
+// lintFailed determines whether any unsuppressed finding is at
+// least as severe as failOn.
+func lintFailed(result *lint.Result, failOn severity.Severity) bool {
+	for _, finding := range result.Unsuppressed() {
+		if finding.Severity.AtLeast(failOn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/lock"
+	"opendeps.org/opendeps/manifest/model"
+	"strings"
+)
+
+var flagUpdateLock bool
+
+// lockCmd represents the lock command
+var lockCmd = &cobra.Command{
+	Use:   "lock OPENDEPS_FILE",
+	Short: "Pin dependency specs in a lock file",
+	Long: `Resolves the specification of each dependency in the OpenDeps file
+and records its location, SHA-256 digest and version in an
+opendeps.lock file adjacent to the manifest.
+
+When a lock file is present, the mock, test and validate commands
+verify the specifications they fetch against it.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestPath, err := discovery.FindManifestFile(args)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		logrus.Debugf("reading opendeps manifest: %v", manifestPath)
+		manifest := model.Parse(manifestPath)
+		applyVendorOverlay(manifestPath, manifest)
+		if err := updateLockFile(manifestPath, manifest); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+}
+
+// addUpdateLockFlag registers the flag used to refresh the lock file
+// on commands that verify against it.
+func addUpdateLockFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flagUpdateLock, "update-lock", false, "Update the lock file with the current dependency specs instead of verifying against it")
+}
+
+func updateLockFile(manifestPath string, manifest *model.OpenDeps) error {
+	lockFile, err := lock.Generate(manifestPath, manifest)
+	if err != nil {
+		return err
+	}
+	lockFilePath := lock.GetLockFilePath(manifestPath)
+	if err := lock.Write(lockFile, lockFilePath); err != nil {
+		return err
+	}
+	logrus.Infof("locked %d dependencies in: %v", len(lockFile.Dependencies), lockFilePath)
+	return nil
+}
+
+// verifyLockFile checks the dependency specs against the lock file, if one
+// exists, or regenerates it if the update flag is set, exiting on failure.
+func verifyLockFile(manifestPath string, manifest *model.OpenDeps) {
+	if err := checkLockFile(manifestPath, manifest); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// checkLockFile checks the dependency specs against the lock file, if one
+// exists, or regenerates it if the update flag is set.
+func checkLockFile(manifestPath string, manifest *model.OpenDeps) error {
+	if flagUpdateLock {
+		return updateLockFile(manifestPath, manifest)
+	}
+
+	lockFilePath := lock.GetLockFilePath(manifestPath)
+	lockFile, err := lock.Load(lockFilePath)
+	if err != nil {
+		return err
+	} else if lockFile == nil {
+		logrus.Debugf("no lock file found at: %v - skipping verification", lockFilePath)
+		return nil
+	}
+
+	mismatches := lock.Verify(manifestPath, manifest, lockFile)
+	if len(mismatches) > 0 {
+		return fmt.Errorf("dependency specs do not match lock file: %v - run with --update-lock to accept changes:\n- %v", lockFilePath, strings.Join(mismatches, "\n- "))
+	}
+	logrus.Debugf("verified %d dependencies against lock file: %v", len(lockFile.Dependencies), lockFilePath)
+	return nil
+}
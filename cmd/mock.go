@@ -17,9 +17,6 @@ limitations under the License.
 package cmd
 
 import (
-	"fmt"
-	"gatehill.io/imposter/engine"
-	"gatehill.io/imposter/engine/docker"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"opendeps.org/opendeps/fileutil"
@@ -27,13 +24,15 @@ import (
 	"opendeps.org/opendeps/manifest/discovery"
 	"opendeps.org/opendeps/manifest/model"
 	"opendeps.org/opendeps/openapi"
+	"opendeps.org/opendeps/spechandlers"
 	"os"
 	"os/signal"
+	"sort"
 	"sync"
 	"syscall"
 )
 
-var flagPort int
+var flagPort, flagGrpcPort int
 
 // mockCmd represents the mock command
 var mockCmd = &cobra.Command{
@@ -41,6 +40,7 @@ var mockCmd = &cobra.Command{
 	Short: "Start live mocks of API dependencies",
 	Long: `Starts a live mock of your API dependencies, based
 on their OpenAPI specifications defined in the OpenDeps file.
+Dependencies with gRPC specs are mocked by a native gRPC server.
 
 This assumes that the specification URL is reachable
 by this tool.`,
@@ -56,46 +56,101 @@ by this tool.`,
 
 		logrus.Debugf("reading opendeps manifest: %v\n", manifestPath)
 		manifest := model.Parse(manifestPath)
+		applyVendorOverlay(manifestPath, manifest)
+		verifyLockFile(manifestPath, manifest)
 
 		bundler.BundleManifest(stagingDir, manifestPath, flagForceOverwrite)
-		openapi.BundleSpecs(stagingDir, manifestPath, manifest, flagForceOverwrite)
-
-		engineType := docker.EnableEngine()
-
-		mockEngine := engine.BuildEngine(engineType, stagingDir, engine.StartOptions{
+		options := openapi.MockOptions{
+			ManifestPath:   manifestPath,
+			StagingDir:     stagingDir,
+			ForceOverwrite: flagForceOverwrite,
 			Port:           flagPort,
-			Version:        "latest",
-			PullPolicy:     engine.PullIfNotPresent,
-			LogLevel:       "DEBUG",
-			ReplaceRunning: true,
-			Deduplicate:    genDeduplicationKey(manifestPath, flagPort),
-		})
-		wg := &sync.WaitGroup{}
-		mockEngine.Start(wg)
-
-		trapExit(wg, mockEngine)
-		wg.Wait()
-	},
-}
+			GrpcPort:       flagGrpcPort,
+		}
+		mocks := startMocks(manifestPath, manifest, options)
+		if len(mocks) == 0 {
+			logrus.Info("no dependencies to mock")
+			return
+		}
 
-// genDeduplicationKey overrides the default deduplication key to a
-// stable value, since the staging dir is dynamic
-func genDeduplicationKey(manifestPath string, port int) string {
-	return fmt.Sprintf("%v:%d", manifestPath, port)
+		interrupted := make(chan struct{})
+		trapExit(func() { close(interrupted) })
+		select {
+		case <-interrupted:
+		case <-anyStopped(mocks):
+			logrus.Warn("a mock stopped - stopping the others")
+		}
+		stopMocks(mocks)
+	},
 }
 
 func init() {
 	mockCmd.Flags().IntVarP(&flagPort, "port", "p", 8080, "Port on which to listen")
+	mockCmd.Flags().IntVar(&flagGrpcPort, "grpc-port", 9090, "Port on which to listen for gRPC dependencies")
+	addServerVarFlag(mockCmd)
+	addUpdateLockFlag(mockCmd)
 	rootCmd.AddCommand(mockCmd)
 }
 
-// listen for an interrupt from the OS, then attempt engine cleanup
-func trapExit(wg *sync.WaitGroup, mockEngine engine.MockEngine) {
-	c := make(chan os.Signal)
+// startMocks starts a mock of the dependencies with specs of each kind,
+// using the handler for that kind, returning the mocks that were started.
+func startMocks(manifestPath string, manifest *model.OpenDeps, options openapi.MockOptions) []openapi.Mock {
+	var depNames []string
+	for depName := range manifest.Dependencies {
+		depNames = append(depNames, depName)
+	}
+	sort.Strings(depNames)
+
+	depsByKind := make(map[string][]openapi.DependencyContext)
+	for _, depName := range depNames {
+		depContext := buildDependencyContext(manifestPath, depName, manifest.Dependencies[depName], nil)
+		kind := spechandlers.Detect(depContext.SpecPath).Kind()
+		depsByKind[kind] = append(depsByKind[kind], depContext)
+	}
+
+	var mocks []openapi.Mock
+	for _, handler := range spechandlers.All() {
+		deps := depsByKind[handler.Kind()]
+		if len(deps) == 0 {
+			continue
+		}
+		mock, err := handler.StartMock(deps, options)
+		if err != nil {
+			stopMocks(mocks)
+			logrus.Fatalf("failed to mock %v dependencies: %v", handler.Kind(), err)
+		} else if mock != nil {
+			mocks = append(mocks, mock)
+		}
+	}
+	return mocks
+}
+
+// anyStopped returns a channel that is closed when any of the mocks stops.
+func anyStopped(mocks []openapi.Mock) <-chan struct{} {
+	stopped := make(chan struct{})
+	var once sync.Once
+	for _, mock := range mocks {
+		go func(done <-chan struct{}) {
+			<-done
+			once.Do(func() { close(stopped) })
+		}(mock.Done())
+	}
+	return stopped
+}
+
+func stopMocks(mocks []openapi.Mock) {
+	for _, mock := range mocks {
+		mock.Stop()
+	}
+}
+
+// listen for an interrupt from the OS, then stop the mocks
+func trapExit(stop func()) {
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
-		println()
-		mockEngine.StopImmediately(wg)
+		logrus.Info("stopping mocks")
+		stop()
 	}()
 }
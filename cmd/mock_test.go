@@ -0,0 +1,55 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"opendeps.org/opendeps/openapi"
+	"testing"
+	"time"
+)
+
+type fakeMock struct {
+	done chan struct{}
+}
+
+func (m *fakeMock) Stop() {}
+
+func (m *fakeMock) Done() <-chan struct{} {
+	return m.done
+}
+
+func TestAnyStopped(t *testing.T) {
+	first, second := &fakeMock{done: make(chan struct{})}, &fakeMock{done: make(chan struct{})}
+	stopped := anyStopped([]openapi.Mock{first, second})
+
+	select {
+	case <-stopped:
+		t.Fatal("anyStopped() closed before any mock stopped")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(second.done)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("anyStopped() not closed after a mock stopped")
+	}
+
+	// a later stop must not close the channel again
+	close(first.done)
+	time.Sleep(10 * time.Millisecond)
+}
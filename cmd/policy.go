@@ -0,0 +1,160 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/manifest/policy"
+	"opendeps.org/opendeps/manifest/severity"
+	"opendeps.org/opendeps/manifest/vendoring"
+	"os"
+	"strings"
+)
+
+// exit statuses of the policy check command
+const (
+	policyExitViolated = 1
+	policyExitError    = 2
+)
+
+var flagPolicyPaths []string
+var flagPolicyOutput string
+
+// policyCmd represents the policy command
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Check manifests against organisation policies",
+	Long: `Checks OpenDeps manifests against policies written as
+CEL expressions, such as forbidding a dependency on a
+legacy service, or limiting the number of required
+dependencies.`,
+}
+
+// policyCheckCmd represents the policy check command
+var policyCheckCmd = &cobra.Command{
+	Use:   "check OPENDEPS_FILE",
+	Short: "Check a manifest against policies",
+	Long: `Evaluates the policies in the given files or directories
+against the resolved manifest and the metadata of its
+dependencies' specs.
+
+Exits with status 1 if a policy with error severity is
+violated, or 2 if the policies cannot be evaluated.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestPath, err := discovery.FindManifestFile(args)
+		if err != nil {
+			exitPolicyError(err)
+		}
+		output := strings.ToLower(flagPolicyOutput)
+		if output != "text" && output != "json" {
+			exitPolicyError(fmt.Errorf("unsupported output [%v] - must be one of: text, json", flagPolicyOutput))
+		}
+		if len(flagPolicyPaths) == 0 {
+			exitPolicyError(fmt.Errorf("no policies given - use --policy to set a policy file or directory"))
+		}
+
+		policies, err := policy.Load(flagPolicyPaths)
+		if err != nil {
+			exitPolicyError(err)
+		}
+
+		logrus.Debugf("reading opendeps manifest: %v", manifestPath)
+		manifest, err := model.Load(manifestPath)
+		if err != nil {
+			exitPolicyError(err)
+		}
+		if err := vendoring.ApplyOverlay(manifestPath, manifest); err != nil {
+			exitPolicyError(err)
+		}
+		input, err := policy.BuildInput(manifestPath, manifest)
+		if err != nil {
+			exitPolicyError(err)
+		}
+
+		violations, errs := policy.Evaluate(policies, input)
+		switch output {
+		case "text":
+			printPolicyViolations(policies, violations)
+		case "json":
+			printPolicyViolationsJson(violations, errs)
+		}
+		for _, err := range errs {
+			logrus.Error(err)
+		}
+
+		if len(errs) > 0 {
+			os.Exit(policyExitError)
+		}
+		for _, violation := range violations {
+			if violation.Severity == severity.Error {
+				os.Exit(policyExitViolated)
+			}
+		}
+	},
+}
+
+func init() {
+	policyCheckCmd.Flags().StringSliceVarP(&flagPolicyPaths, "policy", "p", nil, "Policy file, or directory of policy files (repeatable)")
+	policyCheckCmd.Flags().StringVar(&flagPolicyOutput, "output", "text", "Format of the violations: text or json")
+	policyCmd.AddCommand(policyCheckCmd)
+	rootCmd.AddCommand(policyCmd)
+}
+
+// exitPolicyError reports that the policies could not be evaluated.
+func exitPolicyError(err error) {
+	logrus.Error(strings.TrimSpace(err.Error()))
+	os.Exit(policyExitError)
+}
+
+func printPolicyViolations(policies []policy.Policy, violations []policy.Violation) {
+	for _, violation := range violations {
+		fmt.Printf("%v: %v: %v [%v]\n", violation.Source, violation.Severity, violation.Message, violation.PolicyId)
+	}
+	logrus.Infof("checked %d policies: %d violation(s) found", len(policies), len(violations))
+}
+
+func printPolicyViolationsJson(violations []policy.Violation, errs []error) {
+	report := struct {
+		Passed     bool               `json:"passed"`
+		Violations []policy.Violation `json:"violations"`
+		Errors     []string           `json:"errors"`
+	}{
+		Passed:     len(errs) == 0,
+		Violations: []policy.Violation{},
+		Errors:     []string{},
+	}
+	for _, violation := range violations {
+		if violation.Severity == severity.Error {
+			report.Passed = false
+		}
+		report.Violations = append(report.Violations, violation)
+	}
+	for _, err := range errs {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	marshalled, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logrus.Fatalf("error writing JSON: %v", err)
+	}
+	fmt.Println(string(marshalled))
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/model"
+	"os"
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render OPENDEPS_FILE",
+	Short: "Print the fully resolved manifest",
+	Long: `Prints the OpenDeps manifest after environment variables
+have been substituted, as seen by the other commands.
+
+This is useful for debugging manifests that use
+${VAR} or ${VAR:-default} interpolation.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestPath, err := discovery.FindManifestFile(args)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		logrus.Debugf("reading opendeps manifest: %v", manifestPath)
+		manifest := model.Parse(manifestPath)
+
+		marshalled, err := yaml.Marshal(manifest)
+		if err != nil {
+			logrus.Fatalf("error rendering opendeps manifest: %v: %v", manifestPath, err)
+		}
+		_, err = os.Stdout.Write(marshalled)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+}
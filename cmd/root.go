@@ -19,13 +19,20 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
 	"os"
+	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var flagOffline, flagNoCache, flagStrictEnv bool
+var flagCacheTtl time.Duration
+var flagOverlays []string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -40,6 +47,11 @@ software component needs to run correctly.
 * Language agnostic and cross-platform
 
 Learn more at github.com/opendeps`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		loadProjectConfig(args)
+		applyConfigToFlags(cmd)
+		configureGlobals()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -53,6 +65,12 @@ func init() {
 
 	// Global flags.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.opendeps.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&flagOffline, "offline", false, "Serve remote specs and schemas only from the local cache")
+	rootCmd.PersistentFlags().BoolVar(&flagNoCache, "no-cache", false, "Always fetch remote specs and schemas, bypassing the local cache")
+	rootCmd.PersistentFlags().BoolVar(&flagStrictEnv, "strict-env", false, "Fail if the manifest references an unset environment variable without a default")
+	rootCmd.PersistentFlags().DurationVar(&flagCacheTtl, "cache-ttl", time.Hour, "Period for which cached remote content is used without revalidation")
+	rootCmd.PersistentFlags().StringVarP(&flagEnvironment, "env", "e", "", "Name of the environment to use, selecting its servers and overlay file (e.g. staging)")
+	rootCmd.PersistentFlags().StringSliceVar(&flagOverlays, "overlay", nil, "Overlay file to merge into the manifest, applied after any environment overlay (repeatable)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -70,10 +88,29 @@ func initConfig() {
 		viper.SetConfigName(".opendeps")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// read in environment variables that match, such as OPENDEPS_NON_ZERO_EXIT for --non-zero-exit
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
+		configFiles = append(configFiles, viper.ConfigFileUsed())
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// configureGlobals applies the global flags, once their
+// values have been resolved from all config sources.
+func configureGlobals() {
+	if flagOffline && flagNoCache {
+		cobra.CheckErr(fmt.Errorf("--offline and --no-cache cannot be used together"))
+	}
+	fileutil.ConfigureCache(fileutil.CacheConfig{
+		Disabled: flagNoCache,
+		Offline:  flagOffline,
+		TTL:      flagCacheTtl,
+	})
+	model.ConfigureInterpolation(flagStrictEnv)
+	model.ConfigureOverlays(flagEnvironment, flagOverlays)
+}
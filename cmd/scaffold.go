@@ -17,20 +17,55 @@ limitations under the License.
 package cmd
 
 import (
-	"gatehill.io/imposter/fileutil"
-	imposteropenapi "gatehill.io/imposter/openapi"
+	"bytes"
+	"fmt"
+	imposterfileutil "gatehill.io/imposter/fileutil"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"opendeps.org/opendeps/catalog"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/editor"
 	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/manifest/vendoring"
 	"opendeps.org/opendeps/openapi"
+	"opendeps.org/opendeps/spechandlers"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var flagForceOverwrite bool
+var flagScaffoldMerge, flagScaffoldScan, flagScaffoldRecursive bool
+var flagScaffoldInclude, flagScaffoldExclude, flagScaffoldUrls, flagScaffoldRequired []string
+var flagScaffoldCatalog string
+var flagScaffoldAllRequired bool
+
+// missingSpecComment flags dependencies whose spec no longer exists
+const missingSpecComment = "spec not found by scaffold - remove if no longer needed"
+
+// scaffoldSources are where scaffold looks for dependency specs
+type scaffoldSources struct {
+	Scan        bool
+	FindOptions fileutil.FindOptions
+	Urls        []string
+	Catalog     string
+}
+
+// scaffolded is the content discovered for a manifest
+type scaffolded struct {
+	// Title is the name of the catalog component, if any
+	Title           string
+	Dependencies    map[string]model.Dependency
+	SecurityConfigs map[string]model.SecurityConfig
+
+	// Availabilities are the inferred availability paths, by dependency name
+	Availabilities map[string]*inferredAvailability
+}
 
 // scaffoldCmd represents the scaffold command
 var scaffoldCmd = &cobra.Command{
@@ -38,6 +73,14 @@ var scaffoldCmd = &cobra.Command{
 	Short: "Create an OpenDeps manifest from OpenAPI files",
 	Long: `Creates an OpenDeps manifest from the OpenAPI specification files in a directory.
 
+Specs can also be taken from URLs, with --url, or from the APIs consumed
+by the components in a Backstage catalog file, with --catalog.
+
+If --merge is set and the manifest, opendeps.yaml or opendeps.yml, already
+exists, it is updated in place: newly discovered specs are added, the
+versions of existing dependencies are updated, and dependencies whose local
+spec no longer exists are flagged with a comment. Comments and key order in the manifest are preserved.
+
 If DIR is not specified, the current working directory is used.`,
 	Args: cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -52,85 +95,359 @@ If DIR is not specified, the current working directory is used.`,
 			logrus.Fatal(err)
 		}
 
-		scaffoldManifest(specDir, flagForceOverwrite)
+		sources := scaffoldSources{
+			Scan: flagScaffoldScan,
+			FindOptions: fileutil.FindOptions{
+				Recursive: flagScaffoldRecursive,
+				Include:   flagScaffoldInclude,
+				Exclude:   append([]string{vendoring.VendorDirName}, flagScaffoldExclude...),
+			},
+			Urls:    flagScaffoldUrls,
+			Catalog: flagScaffoldCatalog,
+		}
+		required := flagScaffoldRequired
+		if flagScaffoldAllRequired {
+			required = []string{"*"}
+		}
+		scaffoldManifest(specDir, sources, required, flagForceOverwrite, flagScaffoldMerge)
 	},
 }
 
 func init() {
 	scaffoldCmd.Flags().BoolVarP(&flagForceOverwrite, "force-overwrite", "f", false, "Force overwrite of destination file(s) if already exist")
+	scaffoldCmd.Flags().BoolVar(&flagScaffoldMerge, "merge", false, "Update an existing manifest in place, preserving its comments and key order")
+	scaffoldCmd.Flags().BoolVar(&flagScaffoldScan, "scan", true, "Look for specs in DIR (use --scan=false to use only --url and --catalog)")
+	scaffoldCmd.Flags().BoolVarP(&flagScaffoldRecursive, "recursive", "r", false, "Look for specs in the subdirectories of DIR, other than hidden ones")
+	scaffoldCmd.Flags().StringSliceVar(&flagScaffoldInclude, "include", nil, "Glob pattern, relative to DIR, that spec files must match (e.g. 'apis/**/*.yaml')")
+	scaffoldCmd.Flags().StringSliceVar(&flagScaffoldExclude, "exclude", nil, "Glob pattern, relative to DIR, of files or directories to skip (e.g. '**/test/**')")
+	scaffoldCmd.Flags().StringSliceVar(&flagScaffoldUrls, "url", nil, "URL of a spec to add as a dependency (repeatable)")
+	scaffoldCmd.Flags().StringVar(&flagScaffoldCatalog, "catalog", "", "Path or URL of a Backstage catalog file, such as catalog-info.yaml, whose consumed APIs are added as dependencies")
+	scaffoldCmd.Flags().StringSliceVar(&flagScaffoldRequired, "required", nil, "Glob pattern matching the names or spec paths of dependencies to mark as required (e.g. 'payments*')")
+	scaffoldCmd.Flags().BoolVar(&flagScaffoldAllRequired, "all-required", false, "Mark all dependencies as required")
+	addAvailabilityHintsFlag(scaffoldCmd)
 	rootCmd.AddCommand(scaffoldCmd)
 }
 
-func scaffoldManifest(specDir string, forceOverwrite bool) {
-	openApiSpecs := imposteropenapi.DiscoverOpenApiSpecs(specDir)
-	logrus.Infof("found %d OpenAPI spec(s)", len(openApiSpecs))
+func scaffoldManifest(specDir string, sources scaffoldSources, required []string, forceOverwrite bool, merge bool) {
+	// an existing manifest is found by the same names as other commands use
+	manifestPath := filepath.Join(specDir, "opendeps.yaml")
+	existingPath, err := discovery.FindManifestFile([]string{specDir})
+	if err == nil {
+		manifestPath = existingPath
+	}
+	discovered := discoverDependencies(manifestPath, sources, forceOverwrite)
+	markRequired(discovered.Dependencies, required)
+
+	if existingPath != "" && merge {
+		mergeManifest(existingPath, discovered)
+		return
+	}
 
+	title := discovered.Title
+	if title == "" {
+		title = filepath.Base(specDir)
+	}
 	manifest := model.OpenDeps{
 		OpenDeps: model.OpenDepsSchemaVersion,
 		Info: &model.Info{
-			Title:   "OpenDeps manifest for " + filepath.Base(specDir),
+			Title:   "OpenDeps manifest for " + title,
 			Version: "1.0.0",
 		},
-		Dependencies: make(map[string]model.Dependency),
+		Dependencies: discovered.Dependencies,
+	}
+	if len(discovered.SecurityConfigs) > 0 {
+		manifest.Components = &model.Components{SecurityConfigs: discovered.SecurityConfigs}
 	}
 
-	for _, openApiSpec := range openApiSpecs {
-		spec, err := openapi.Parse(openApiSpec)
-		if err != nil {
-			logrus.Fatalf("error parsing openapi spec: %v: %v", openApiSpec, err)
+	manifestPath = imposterfileutil.GenerateFilePathAdjacentToFile(filepath.Join(specDir, "opendeps"), ".yaml", forceOverwrite)
+	e, err := editor.New(&manifest)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	annotateAvailabilities(e, discovered.Availabilities)
+	if err := e.Save(manifestPath); err != nil {
+		logrus.Fatalf("error writing opendeps manifest file: %v: %v", manifestPath, err)
+	}
+
+	logrus.Infof("wrote OpenDeps manifest file: %v", manifestPath)
+}
+
+// discoverDependencies builds a dependency for each spec found in the
+// sources, and a security config for each of the specs' security schemes.
+func discoverDependencies(manifestPath string, sources scaffoldSources, forceOverwrite bool) scaffolded {
+	manifestDir := filepath.Dir(manifestPath)
+	discovered := scaffolded{
+		Dependencies:    make(map[string]model.Dependency),
+		SecurityConfigs: make(map[string]model.SecurityConfig),
+		Availabilities:  make(map[string]*inferredAvailability),
+	}
+	specKeys := make(map[string]bool)
+	add := func(name string, dep model.Dependency, spec *openapi.PartialModel, availability *inferredAvailability) {
+		specKey := getSpecKey(manifestDir, dep.Spec)
+		if specKeys[specKey] {
+			logrus.Debugf("skipping spec already added: %v", dep.Spec)
+			return
 		}
+		specKeys[specKey] = true
 
-		depName := strings.TrimSuffix(filepath.Base(openApiSpec), filepath.Ext(openApiSpec))
-		manifest.Dependencies[depName] = model.Dependency{
-			Summary: spec.Info.Title,
-			Spec:    "./" + filepath.Base(openApiSpec),
-			Version: spec.Info.Version,
-			Availability: &model.Availability{
-				Path: determineAvailabilityPath(spec),
-			},
+		depName := name
+		for i := 2; ; i++ {
+			if _, found := discovered.Dependencies[depName]; !found {
+				break
+			}
+			depName = fmt.Sprintf("%v_%d", name, i)
+		}
+		discovered.Dependencies[depName] = dep
+		if availability != nil {
+			discovered.Availabilities[depName] = availability
+		}
+		if spec != nil {
+			addSecurityConfigs(discovered.SecurityConfigs, depName, spec)
 		}
 	}
 
-	manifestPath := fileutil.GenerateFilePathAdjacentToFile(filepath.Join(specDir, "opendeps"), ".yaml", forceOverwrite)
-	file, err := os.Create(manifestPath)
-	if err != nil {
-		logrus.Fatalf("error creating opendeps manifest file: %v: %v", manifestPath, err)
+	// catalog APIs come first, so their names are used for specs also found by scanning
+	if sources.Catalog != "" {
+		discovered.Title = addCatalogDependencies(manifestPath, sources.Catalog, forceOverwrite, add)
 	}
-	defer file.Close()
 
-	marshalled, err := yaml.Marshal(manifest)
-	_, err = file.Write(marshalled)
-	if err != nil {
-		logrus.Fatalf("error writing opendeps manifest file: %v: %v", manifestPath, err)
+	for _, specUrl := range sources.Urls {
+		dep, spec, availability := buildDependency(manifestPath, specUrl, false)
+		add(suggestDependencyName(specUrl, dep), dep, spec, availability)
 	}
 
-	logrus.Infof("wrote OpenDeps manifest file: %v", manifestPath)
+	if sources.Scan {
+		for _, handler := range spechandlers.All() {
+			specs, err := handler.DiscoverSpecs(manifestDir, sources.FindOptions)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if len(specs) > 0 || handler == openapi.Handler {
+				logrus.Infof("found %d %v spec(s)", len(specs), handler.Kind())
+			}
+			for _, specPath := range specs {
+				specLocation := makeSpecLocationRelative(manifestDir, specPath)
+				dep, spec, availability := buildDependency(manifestPath, specLocation, false)
+				// named after the spec file, as scaffold always has, so
+				// merging into existing manifests keeps their names
+				add(strings.TrimSuffix(filepath.Base(specPath), filepath.Ext(specPath)), dep, spec, availability)
+			}
+		}
+	}
+	return discovered
 }
 
-func determineAvailabilityPath(spec *openapi.PartialModel) string {
-	var getPaths []string
-	for path, operations := range spec.Paths {
-		if _, found := operations["get"]; found {
-			getPaths = append(getPaths, path)
+// addSecurityConfigs adds a security config for each of the spec's security
+// schemes. If a config of the same name, but different content, exists, the
+// name is prefixed with that of the dependency.
+func addSecurityConfigs(configs map[string]model.SecurityConfig, depName string, spec *openapi.PartialModel) {
+	var schemeNames []string
+	for schemeName := range spec.Components.SecuritySchemes {
+		schemeNames = append(schemeNames, schemeName)
+	}
+	sort.Strings(schemeNames)
+
+	for _, schemeName := range schemeNames {
+		config := buildSecurityConfig(spec.Components.SecuritySchemes[schemeName])
+		name := schemeName
+		if existing, found := configs[name]; found && !reflect.DeepEqual(existing, config) {
+			name = depName + "_" + schemeName
 		}
+		configs[name] = config
 	}
-	if len(getPaths) == 1 {
-		return getPaths[0]
-	} else {
-		for _, path := range getPaths {
-			for _, pathHint := range getAvailabilityPathHints() {
-				if path == pathHint {
-					return path
+}
+
+func buildSecurityConfig(scheme openapi.SecurityScheme) model.SecurityConfig {
+	config := model.SecurityConfig{
+		SecurityConfigType: scheme.Type,
+		Scheme:             strings.ToLower(scheme.Scheme),
+	}
+	if scheme.Type == "apiKey" && scheme.In == "header" {
+		config.Headers = []string{scheme.Name}
+	} else if scheme.Type == "http" || scheme.Type == "oauth2" || scheme.Type == "openIdConnect" {
+		config.Headers = []string{"Authorization"}
+	}
+	return config
+}
+
+// markRequired marks the dependencies whose name or spec location
+// matches one of the glob patterns as required.
+func markRequired(dependencies map[string]model.Dependency, patterns []string) {
+	for depName, dep := range dependencies {
+		for _, pattern := range patterns {
+			if fileutil.MatchGlob(pattern, depName) || fileutil.MatchGlob(pattern, strings.TrimPrefix(dep.Spec, "./")) {
+				dep.Required = true
+				dependencies[depName] = dep
+				break
+			}
+		}
+	}
+}
+
+// addCatalogDependencies adds a dependency for each API consumed by the
+// components in the Backstage catalog file, returning the name of the
+// component, if there is only one. Inline API definitions are written to
+// files next to the manifest.
+func addCatalogDependencies(manifestPath string, catalogLocation string, forceOverwrite bool, add func(string, model.Dependency, *openapi.PartialModel, *inferredAvailability)) string {
+	manifestDir := filepath.Dir(manifestPath)
+	if !fileutil.IsRemote(catalogLocation) {
+		catalogLocation, _ = filepath.Abs(catalogLocation)
+	}
+	c, err := catalog.Load(catalogLocation)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	apiRefs := c.ConsumedApis()
+	logrus.Infof("found %d consumed API(s) in catalog: %v", len(apiRefs), catalogLocation)
+	for _, apiRef := range apiRefs {
+		api, err := c.GetApi(apiRef)
+		if err != nil {
+			logrus.Warnf("skipping consumed API: %v", err)
+			continue
+		}
+		if !strings.EqualFold(api.Type, "openapi") && !strings.EqualFold(api.Type, "asyncapi") && !strings.EqualFold(api.Type, "graphql") {
+			logrus.Warnf("skipping consumed API [%v] of unsupported type: %v", apiRef, api.Type)
+			continue
+		}
+
+		var spec string
+		if api.Location != "" {
+			spec = makeSpecLocationRelative(manifestDir, api.Location)
+		} else {
+			ext := ".yaml"
+			if strings.EqualFold(api.Type, "graphql") {
+				ext = ".graphql"
+			} else if strings.HasPrefix(strings.TrimSpace(string(api.Content)), "{") {
+				ext = ".json"
+			}
+			specPath := filepath.Join(manifestDir, api.Name+ext)
+			if existing, err := ioutil.ReadFile(specPath); err != nil || !bytes.Equal(existing, api.Content) {
+				specPath = imposterfileutil.GenerateFilePathAdjacentToFile(filepath.Join(manifestDir, api.Name), ext, forceOverwrite)
+				if err := ioutil.WriteFile(specPath, api.Content, 0644); err != nil {
+					logrus.Fatalf("error writing inline definition of API [%v]: %v", apiRef, err)
 				}
+				logrus.Infof("wrote inline definition of API [%v] to: %v", apiRef, specPath)
 			}
+			spec = makeSpecLocationRelative(manifestDir, specPath)
+		}
+
+		dep, openapiSpec, availability := buildDependency(manifestPath, spec, false)
+		if api.Title != "" {
+			dep.Summary = api.Title
+		}
+		if dep.Description == "" {
+			dep.Description = strings.TrimSpace(api.Description)
 		}
+		add(api.Name, dep, openapiSpec, availability)
+	}
+
+	if components := c.Components(); len(components) == 1 {
+		return components[0].Metadata.Name
+	}
+	return ""
+}
+
+// makeSpecLocationRelative returns the location of a local spec relative
+// to the manifest directory; remote locations are returned unchanged.
+func makeSpecLocationRelative(manifestDir string, location string) string {
+	if fileutil.IsRemote(location) {
+		return location
+	}
+	relPath, err := filepath.Rel(manifestDir, location)
+	if err != nil {
+		return location
+	}
+	if relPath = filepath.ToSlash(relPath); strings.HasPrefix(relPath, "../") {
+		return relPath
+	}
+	return "./" + relPath
+}
+
+// mergeManifest updates the existing manifest with the discovered dependencies
+// and security configs. Dependencies are matched by spec location, so renamed
+// dependencies are kept. Existing security configs are not changed.
+func mergeManifest(manifestPath string, discovered scaffolded) {
+	e, err := editor.Load(manifestPath)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	manifestDir := filepath.Dir(manifestPath)
+
+	existingBySpec := make(map[string]string)
+	for _, depName := range e.DependencyNames() {
+		if spec := e.DependencySpec(depName); spec != "" {
+			existingBySpec[getSpecKey(manifestDir, spec)] = depName
+		}
+	}
+
+	var discoveredNames []string
+	for depName := range discovered.Dependencies {
+		discoveredNames = append(discoveredNames, depName)
+	}
+	sort.Strings(discoveredNames)
+
+	var added, updated, missing int
+	for _, depName := range discoveredNames {
+		dep := discovered.Dependencies[depName]
+		if existingName, found := existingBySpec[getSpecKey(manifestDir, dep.Spec)]; found {
+			if err := e.SetDependency(existingName, model.Dependency{Version: dep.Version, Required: dep.Required}); err != nil {
+				logrus.Fatal(err)
+			}
+			if e.DependencyComment(existingName) == missingSpecComment {
+				e.SetDependencyComment(existingName, "")
+			}
+			updated++
+			continue
+		}
+
+		name := depName
+		for i := 2; e.HasDependency(name); i++ {
+			name = fmt.Sprintf("%v_%d", depName, i)
+		}
+		if err := e.SetDependency(name, dep); err != nil {
+			logrus.Fatal(err)
+		}
+		annotateAvailability(e, name, discovered.Availabilities[depName])
+		logrus.Infof("added dependency [%v] for spec: %v", name, dep.Spec)
+		added++
+	}
+
+	for specKey, depName := range existingBySpec {
+		if fileutil.IsRemote(specKey) {
+			continue
+		}
+		if _, err := os.Stat(specKey); os.IsNotExist(err) {
+			logrus.Warnf("spec for dependency [%v] no longer exists: %v", depName, specKey)
+			e.SetDependencyComment(depName, missingSpecComment)
+			missing++
+		}
+	}
+
+	var configNames []string
+	for name := range discovered.SecurityConfigs {
+		configNames = append(configNames, name)
+	}
+	sort.Strings(configNames)
+	for _, name := range configNames {
+		if added, err := e.AddSecurityConfig(name, discovered.SecurityConfigs[name]); err != nil {
+			logrus.Fatal(err)
+		} else if added {
+			logrus.Infof("added security config: %v", name)
+		}
+	}
+
+	if err := e.Save(manifestPath); err != nil {
+		logrus.Fatalf("error writing opendeps manifest file: %v: %v", manifestPath, err)
 	}
-	return "/"
+	logrus.Infof("merged OpenDeps manifest file (%d added, %d updated, %d missing): %v", added, updated, missing, manifestPath)
 }
 
-func getAvailabilityPathHints() []string {
-	return []string{
-		"/healthz",
-		"/system/status",
+// getSpecKey returns the absolute path of a local spec, or the URL of a
+// remote one, so that specs can be compared regardless of how they are written.
+func getSpecKey(manifestDir string, spec string) string {
+	if fileutil.IsRemote(spec) {
+		return spec
 	}
+	return filepath.Clean(filepath.Join(manifestDir, spec))
 }
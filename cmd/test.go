@@ -17,16 +17,12 @@ limitations under the License.
 package cmd
 
 import (
-	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"net/http"
-	"opendeps.org/opendeps/fileutil"
 	"opendeps.org/opendeps/manifest/discovery"
 	"opendeps.org/opendeps/manifest/model"
-	"opendeps.org/opendeps/openapi"
+	"opendeps.org/opendeps/spechandlers"
 	"os"
-	"strings"
 )
 
 var flagNonZeroExit, flagContinueIfDown, flagRequireOptional bool
@@ -66,16 +62,22 @@ func init() {
 	testCmd.Flags().BoolVarP(&flagContinueIfDown, "continue", "c", true, "Continue to check further dependencies if one or more is down")
 	testCmd.Flags().BoolVarP(&flagRequireOptional, "require-optional", "o", false, "Require optional dependencies to be available")
 	testCmd.Flags().StringToStringVarP(&flagServers, "server", "s", nil, "Override server base URL for a dependency (e.g. foo_service=https://example.com)")
+	addServerVarFlag(testCmd)
+	addUpdateLockFlag(testCmd)
 }
 
 func testDependencies(manifestPath string) (successful int, tested int) {
 	logrus.Debugf("reading opendeps manifest: %v", manifestPath)
 	manifest := model.Parse(manifestPath)
+	applyVendorOverlay(manifestPath, manifest)
+	verifyLockFile(manifestPath, manifest)
+
+	environment := resolveEnvironment(manifestPath, manifest)
 
 	logrus.Infof("testing %d dependencies", len(manifest.Dependencies))
 	available := 0
 	for depName, dep := range manifest.Dependencies {
-		err := testDependency(manifestPath, depName, dep)
+		err := testDependency(manifestPath, depName, dep, environment)
 		if err != nil {
 			if dep.Required || flagRequireOptional {
 				logrus.Warnf("\u274C unavailable: %v: %v", dep.Summary, err)
@@ -93,60 +95,9 @@ func testDependencies(manifestPath string) (successful int, tested int) {
 	return available, len(manifest.Dependencies)
 }
 
-func testDependency(manifestPath string, depName string, dep model.Dependency) error {
-	if "" != dep.Availability.Security {
-		logrus.Warnf("security configuration for availability endpoints is not supported\n")
-	}
-
-	var url string
-	if "" != dep.Availability.Url {
-		// fully qualified
-		url = dep.Availability.Url
-
-	} else if "" != dep.Availability.Path {
-		// relative - use openapi spec servers as base path
-		basePath, err := determineBasePath(manifestPath, depName, dep)
-		if err != nil {
-			return err
-		}
-		trimmedBasePath := strings.TrimSuffix(basePath, "/")
-		trimmedPath := strings.TrimPrefix(dep.Availability.Path, "/")
-		url = fmt.Sprintf("%v/%v", trimmedBasePath, trimmedPath)
-
-	} else {
-		panic(fmt.Errorf("No availability URL or path for %v\n", dep.Summary))
-	}
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to reach availability URL [%v]: %v\n", url, err)
-	} else {
-		logrus.Debugf("checked availability [%v]: %s\n", dep.Summary, resp.Status)
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return fmt.Errorf("failed to reach availability URL [%v]: %s\n", url, resp.Status)
-		}
-	}
-	return nil
-}
-
-func determineBasePath(manifestPath string, depName string, dependency model.Dependency) (string, error) {
-	if serverUrl, found := flagServers[depName]; found {
-		logrus.Debugf("determined server [%v] from overrides", serverUrl)
-		return serverUrl, nil
-
-	} else {
-		specNormalisedPath := fileutil.MakeAbsoluteRelativeToFile(dependency.Spec, manifestPath)
-		openapiSpec, err := openapi.Parse(specNormalisedPath)
-		if err != nil {
-			return "", fmt.Errorf("failed to parse spec [%v]: %v\n", specNormalisedPath, err)
-		}
-		if len(openapiSpec.Servers) == 0 {
-			return "", fmt.Errorf("no servers found in spec [%v]\n", specNormalisedPath)
-		} else if len(openapiSpec.Servers) > 1 {
-			logrus.Warnf("more than 1 server found in spec [%v] - using first\n", specNormalisedPath)
-		}
-		serverUrl := openapiSpec.Servers[0].Url
-		logrus.Debugf("determined server [%v] from openapi spec [%v]", serverUrl, specNormalisedPath)
-		return serverUrl, nil
-	}
+func testDependency(manifestPath string, depName string, dep model.Dependency, environment *model.Environment) error {
+	depContext := buildDependencyContext(manifestPath, depName, dep, environment)
+	handler := spechandlers.Detect(depContext.SpecPath)
+	logrus.Debugf("checking availability of %v dependency %v", handler.Kind(), depName)
+	return handler.Check(depContext)
 }
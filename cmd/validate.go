@@ -17,17 +17,34 @@ limitations under the License.
 package cmd
 
 import (
+	encjson "encoding/json"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"github.com/xeipuuv/gojsonschema"
 	"io/ioutil"
-	"log"
+	"opendeps.org/opendeps/fileutil"
 	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/editor"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/manifest/vendoring"
+	"opendeps.org/opendeps/sarif"
+	"opendeps.org/opendeps/spechandlers"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 )
 
+const (
+	schemaRuleId         = "opendeps-schema"
+	dependencySpecRuleId = "opendeps-dependency-spec"
+	manifestRuleId       = "opendeps-manifest"
+)
+
+var flagValidateOutput string
+
 // validateCmd represents the validate command
 var validateCmd = &cobra.Command{
 	Use:   "validate OPENDEPS_FILE",
@@ -35,29 +52,83 @@ var validateCmd = &cobra.Command{
 	Long:  `Validates a YAML manifest file against the OpenDeps schema.`,
 	Args:  cobra.RangeArgs(0, 1),
 	Run: func(cmd *cobra.Command, args []string) {
+		output := strings.ToLower(flagValidateOutput)
+		if output != "text" && output != "json" && output != "sarif" {
+			logrus.Fatalf("unsupported output [%v] - must be one of: text, json, sarif", flagValidateOutput)
+		}
 		manifestPath, err := discovery.FindManifestFile(args)
 		if err != nil {
-			logrus.Fatal(err)
+			printValidationErrors(output, manifestPath, []validationError{{RuleId: manifestRuleId, Message: err.Error()}})
+			os.Exit(1)
 		}
 		logrus.Infof("validating opendeps manifest: %v\n", manifestPath)
 
-		json, err := loadSpecAsJson(manifestPath)
+		// the errors found before validation failed,
+		// if it did, are reported along with the failure
+		validationErrors, err := validateManifest(manifestPath)
 		if err != nil {
-			log.Fatal(err)
+			validationErrors = append(validationErrors, validationError{RuleId: manifestRuleId, Message: err.Error()})
+		}
+		printValidationErrors(output, manifestPath, validationErrors)
+		if len(validationErrors) > 0 {
+			os.Exit(1)
 		}
-
-		validateSpec(json)
 	},
 }
 
+// validationError is a violation of the OpenDeps schema, a problem with the
+// spec of a dependency, or a failure to validate the manifest. It is located
+// by its position in the file it comes from, if known.
+type validationError struct {
+	RuleId  string `json:"rule"`
+	Pointer string `json:"pointer"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
 func init() {
 	rootCmd.AddCommand(validateCmd)
+	addUpdateLockFlag(validateCmd)
+
+	validateCmd.Flags().StringVar(&flagValidateOutput, "output", "text", "Format of the validation errors: text, json or sarif")
+}
+
+// validateManifest validates the manifest against the OpenDeps schema then,
+// if it can be read into the model, each dependency against its spec. If
+// validation cannot be completed, an error is returned, along with the
+// errors found before it failed.
+func validateManifest(manifestPath string) ([]validationError, error) {
+	json, err := loadSpecAsJson(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	locator := newManifestLocator(manifestPath)
+	validationErrors, err := validateSpec(locator, json)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := model.Load(manifestPath)
+	if err != nil {
+		return validationErrors, err
+	}
+	if err := vendoring.ApplyOverlay(manifestPath, manifest); err != nil {
+		return validationErrors, err
+	}
+	if err := checkLockFile(manifestPath, manifest); err != nil {
+		return validationErrors, err
+	}
+	return append(validationErrors, validateDependencySpecs(manifestPath, manifest, locator)...), nil
 }
 
+// loadSpecAsJson reads the manifest, after resolving the manifests it extends
+// and any overlays, and converts it to JSON.
 func loadSpecAsJson(manifestPath string) ([]byte, error) {
-	y, err := ioutil.ReadFile(manifestPath)
+	y, err := model.ResolveRaw(manifestPath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	j, err := yaml.YAMLToJSON(y)
@@ -67,21 +138,267 @@ func loadSpecAsJson(manifestPath string) ([]byte, error) {
 	return j, nil
 }
 
-func validateSpec(json []byte) {
-	schemaLoader := gojsonschema.NewReferenceLoader("https://raw.githubusercontent.com/opendeps/specification/main/opendeps-specification.json")
+// loadSchema reads the schema through the content cache, so
+// validation works offline once the cache is warm.
+func loadSchema(schemaUrl string) ([]byte, error) {
+	reader, err := fileutil.ReadContent(schemaUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// validateSpec validates the resolved manifest against the OpenDeps schema,
+// returning the violations, located by the locator.
+func validateSpec(locator *manifestLocator, json []byte) ([]validationError, error) {
+	schema, err := loadSchema("https://raw.githubusercontent.com/opendeps/specification/main/opendeps-specification.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenDeps schema: %v", err)
+	}
+	schemaLoader := gojsonschema.NewBytesLoader(schema)
 	documentLoader := gojsonschema.NewBytesLoader(json)
 
 	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
 	if err != nil {
-		log.Fatal(err.Error())
+		return nil, fmt.Errorf("failed to validate against OpenDeps schema: %v", err)
 	}
-
 	if result.Valid() {
+		return nil, nil
+	}
+
+	var validationErrors []validationError
+	for _, desc := range result.Errors() {
+		validationErrors = append(validationErrors, newValidationError(locator, schemaRuleId, toJsonPointer(desc.Context()), desc.Description()))
+	}
+	sort.SliceStable(validationErrors, func(i, j int) bool {
+		a, b := validationErrors[i], validationErrors[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Message < b.Message
+	})
+	return validationErrors, nil
+}
+
+// escapeJsonPointerToken escapes a key for use in a JSON pointer.
+func escapeJsonPointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// newValidationError returns the error for the rule at the JSON pointer,
+// located by the locator.
+func newValidationError(locator *manifestLocator, ruleId string, pointer string, message string) validationError {
+	validationErr := validationError{
+		RuleId:  ruleId,
+		Pointer: pointer,
+		Message: message,
+	}
+	validationErr.File, validationErr.Line, validationErr.Column = locator.locate(pointer)
+	return validationErr
+}
+
+// manifestLocator locates values of the resolved manifest in
+// the files that the manifest is resolved from.
+type manifestLocator struct {
+	manifestPath string
+
+	// sources are the files the manifest is resolved from that can
+	// be read, in order of precedence, keyed by their location
+	sources []string
+	editors map[string]*editor.Editor
+
+	// composed is true if the manifest is resolved from more than one file
+	composed bool
+}
+
+func newManifestLocator(manifestPath string) *manifestLocator {
+	locator := &manifestLocator{
+		manifestPath: manifestPath,
+		editors:      make(map[string]*editor.Editor),
+	}
+	sources, err := model.ResolveSources(manifestPath)
+	if err != nil {
+		logrus.Debugf("cannot locate validation errors in manifest: %v", err)
+		sources = []string{manifestPath}
+	}
+	locator.composed = len(sources) > 1
+	for _, source := range sources {
+		if fileutil.IsRemote(source) {
+			continue
+		}
+		sourceEditor, err := editor.Load(source)
+		if err != nil {
+			logrus.Debugf("cannot locate validation errors in [%v]: %v", source, err)
+			continue
+		}
+		locator.sources = append(locator.sources, source)
+		locator.editors[source] = sourceEditor
+	}
+	return locator
+}
+
+// locate returns the file, line and column of the value at the JSON pointer,
+// from the first file, in order of precedence, that has the value. If none
+// has, the value is located at its closest ancestor in the manifest file,
+// unless the manifest is resolved from more than one file, when the file the
+// value comes from is unknown, so an empty file, line and column are returned.
+func (l *manifestLocator) locate(pointer string) (string, int, int) {
+	if pointer != "" {
+		for _, source := range l.sources {
+			if line, column, found := l.editors[source].Locate(pointer); found {
+				return source, line, column
+			}
+		}
+		if l.composed {
+			return "", 0, 0
+		}
+	}
+	if manifestEditor, found := l.editors[l.manifestPath]; found {
+		line, column, _ := manifestEditor.Locate(pointer)
+		return l.manifestPath, line, column
+	}
+	return "", 0, 0
+}
+
+// toJsonPointer converts the context of a validation error, such
+// as '(root).dependencies.foo', to a JSON pointer.
+func toJsonPointer(context *gojsonschema.JsonContext) string {
+	if context == nil {
+		return ""
+	}
+	const delimiter = "\x00"
+	var pointer strings.Builder
+	for _, token := range strings.Split(context.String(delimiter), delimiter)[1:] {
+		pointer.WriteString("/")
+		pointer.WriteString(escapeJsonPointerToken(token))
+	}
+	return pointer.String()
+}
+
+func printValidationErrors(output string, manifestPath string, validationErrors []validationError) {
+	switch output {
+	case "text":
+		logValidationErrors(manifestPath, validationErrors)
+	case "json":
+		printValidationJson(manifestPath, validationErrors)
+	case "sarif":
+		printValidationSarif(manifestPath, validationErrors)
+	}
+}
+
+func logValidationErrors(manifestPath string, validationErrors []validationError) {
+	if len(validationErrors) == 0 {
 		logrus.Infof("The document is valid\n")
-	} else {
-		logrus.Warnf("The document is not valid. see errors :\n")
-		for _, desc := range result.Errors() {
-			logrus.Warnf("- %s\n", desc)
+		return
+	}
+	logrus.Warnf("The document is not valid. see errors :\n")
+	for _, validationErr := range validationErrors {
+		pointer := validationErr.Pointer
+		if pointer == "" {
+			pointer = "/"
+		}
+		if validationErr.File != "" {
+			logrus.Warnf("- %v:%d:%d: %v: %v\n", validationErr.File, validationErr.Line, validationErr.Column, pointer, validationErr.Message)
+		} else if validationErr.RuleId == manifestRuleId {
+			logrus.Warnf("- %v\n", validationErr.Message)
+		} else {
+			logrus.Warnf("- %v: %v\n", pointer, validationErr.Message)
+		}
+	}
+}
+
+func printValidationJson(manifestPath string, validationErrors []validationError) {
+	report := struct {
+		Manifest string            `json:"manifest"`
+		Valid    bool              `json:"valid"`
+		Errors   []validationError `json:"errors"`
+	}{
+		Manifest: manifestPath,
+		Valid:    len(validationErrors) == 0,
+		Errors:   validationErrors,
+	}
+	if report.Errors == nil {
+		report.Errors = []validationError{}
+	}
+	marshalled, err := encjson.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logrus.Fatalf("error writing JSON: %v", err)
+	}
+	fmt.Println(string(marshalled))
+}
+
+// printValidationSarif writes the validation errors as a SARIF log, with
+// locations relative to the working directory.
+func printValidationSarif(manifestPath string, validationErrors []validationError) {
+	rules := []sarif.Rule{{
+		Id:          schemaRuleId,
+		Description: "The manifest must be valid against the OpenDeps schema",
+		Level:       "error",
+	}, {
+		Id:          dependencySpecRuleId,
+		Description: "Each dependency must be consistent with its spec",
+		Level:       "error",
+	}, {
+		Id:          manifestRuleId,
+		Description: "The manifest, the files it is resolved from and its lock file must be readable",
+		Level:       "error",
+	}}
+	var results []sarif.Result
+	for _, validationErr := range validationErrors {
+		message := validationErr.Message
+		if validationErr.Pointer != "" {
+			message = validationErr.Pointer + ": " + message
+		}
+		results = append(results, sarif.Result{
+			RuleId:  validationErr.RuleId,
+			Level:   "error",
+			Message: message,
+			File:    validationErr.File,
+			Line:    validationErr.Line,
+			Column:  validationErr.Column,
+		})
+	}
+	wd, _ := os.Getwd()
+	marshalled, err := sarif.Marshal(manifestPath, wd, rules, results)
+	if err != nil {
+		logrus.Fatalf("error writing SARIF: %v", err)
+	}
+	fmt.Println(string(marshalled))
+}
+
+// validateDependencySpecs checks each dependency against its spec, using
+// the handler for the kind of spec, such as OpenAPI, AsyncAPI, gRPC or GraphQL,
+// returning the problems, located at the field of the dependency they concern.
+func validateDependencySpecs(manifestPath string, manifest *model.OpenDeps, locator *manifestLocator) []validationError {
+	var depNames []string
+	for depName := range manifest.Dependencies {
+		depNames = append(depNames, depName)
+	}
+	sort.Strings(depNames)
+
+	var validationErrors []validationError
+	for _, depName := range depNames {
+		depContext := buildDependencyContext(manifestPath, depName, manifest.Dependencies[depName], nil)
+		problems := spechandlers.Detect(depContext.SpecPath).Validate(depContext)
+		if len(problems) == 0 {
+			logrus.Debugf("dependency [%v] spec is valid", depName)
+			continue
+		}
+		depPointer := "/dependencies/" + escapeJsonPointerToken(depName)
+		for _, problem := range problems {
+			pointer := problem.Pointer
+			if pointer == "" {
+				pointer = "/spec"
+			}
+			validationErrors = append(validationErrors, newValidationError(locator, dependencySpecRuleId, depPointer+pointer, problem.Message))
 		}
 	}
+	return validationErrors
 }
@@ -0,0 +1,163 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/xeipuuv/gojsonschema"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToJsonPointer(t *testing.T) {
+	tests := []struct {
+		name    string
+		context *gojsonschema.JsonContext
+		want    string
+	}{
+		{name: "no context", context: nil, want: ""},
+		{name: "root", context: newJsonContext(), want: ""},
+		{name: "nested keys", context: newJsonContext("dependencies", "foo_service", "spec"), want: "/dependencies/foo_service/spec"},
+		{name: "array index", context: newJsonContext("dependencies", "foo_service", "x-channels", "0"), want: "/dependencies/foo_service/x-channels/0"},
+		{name: "escaped key", context: newJsonContext("dependencies", "a/b~c"), want: "/dependencies/a~1b~0c"},
+		{name: "key containing a dot", context: newJsonContext("dependencies", "foo.service"), want: "/dependencies/foo.service"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toJsonPointer(tt.context); got != tt.want {
+				t.Errorf("toJsonPointer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newJsonContext returns the context of a validation error
+// at the path, as gojsonschema builds it.
+func newJsonContext(path ...string) *gojsonschema.JsonContext {
+	context := gojsonschema.NewJsonContext("(root)", nil)
+	for _, token := range path {
+		context = gojsonschema.NewJsonContext(token, context)
+	}
+	return context
+}
+
+func TestManifestLocator_locate(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		pointer    string
+		wantFile   string
+		wantLine   int
+		wantColumn int
+	}{
+		{
+			name:       "root",
+			files:      map[string]string{"opendeps.yaml": "info:\n  title: foo\n"},
+			pointer:    "",
+			wantFile:   "opendeps.yaml",
+			wantLine:   1,
+			wantColumn: 1,
+		},
+		{
+			name:       "value in manifest",
+			files:      map[string]string{"opendeps.yaml": "info:\n  title: foo\n"},
+			pointer:    "/info/title",
+			wantFile:   "opendeps.yaml",
+			wantLine:   2,
+			wantColumn: 3,
+		},
+		{
+			name:       "missing value located at its ancestor",
+			files:      map[string]string{"opendeps.yaml": "info:\n  title: foo\n"},
+			pointer:    "/info/version",
+			wantFile:   "opendeps.yaml",
+			wantLine:   1,
+			wantColumn: 1,
+		},
+		{
+			name: "value inherited from extended manifest",
+			files: map[string]string{
+				"opendeps.yaml": "extends: base.yaml\ninfo:\n  title: foo\n",
+				"base.yaml":     "dependencies:\n  bar:\n    spec: bar.yaml\n",
+			},
+			pointer:    "/dependencies/bar/spec",
+			wantFile:   "base.yaml",
+			wantLine:   3,
+			wantColumn: 5,
+		},
+		{
+			name: "value overridden by manifest",
+			files: map[string]string{
+				"opendeps.yaml": "extends: base.yaml\ninfo:\n  title: foo\n",
+				"base.yaml":     "info:\n  title: base\n",
+			},
+			pointer:    "/info/title",
+			wantFile:   "opendeps.yaml",
+			wantLine:   3,
+			wantColumn: 3,
+		},
+		{
+			name: "missing value not located in composed manifest",
+			files: map[string]string{
+				"opendeps.yaml": "extends: base.yaml\ninfo:\n  title: foo\n",
+				"base.yaml":     "info:\n  title: base\n",
+			},
+			pointer: "/info/version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files)
+			locator := newManifestLocator(filepath.Join(dir, "opendeps.yaml"))
+
+			file, line, column := locator.locate(tt.pointer)
+			wantFile := ""
+			if tt.wantFile != "" {
+				wantFile = filepath.Join(dir, tt.wantFile)
+			}
+			if file != wantFile || line != tt.wantLine || column != tt.wantColumn {
+				t.Errorf("locate() = %v:%d:%d, want %v:%d:%d", file, line, column, wantFile, tt.wantLine, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestValidateManifest_loadFailure(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"opendeps.yaml": "extends: missing.yaml\n"})
+
+	_, err := validateManifest(filepath.Join(dir, "opendeps.yaml"))
+	if err == nil {
+		t.Errorf("validateManifest() expected error for manifest extending a missing file")
+	}
+}
+
+// writeFiles writes the files, keyed by their path, to a temporary
+// directory, returning the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
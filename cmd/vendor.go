@@ -0,0 +1,99 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/discovery"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/manifest/vendoring"
+	"os"
+)
+
+var flagVendorCheck bool
+
+// vendorCmd represents the vendor command
+var vendorCmd = &cobra.Command{
+	Use:   "vendor OPENDEPS_FILE",
+	Short: "Copy dependency specs into the repository",
+	Long: `Downloads the specification of each dependency in the OpenDeps file,
+including any files it references using $ref, into an opendeps_vendor
+directory adjacent to the manifest.
+
+When the vendor directory is present, other commands read the
+vendored copies instead of fetching the specifications.
+
+Use --check to report differences between the vendored copies
+and their sources.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestPath, err := discovery.FindManifestFile(args)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		logrus.Debugf("reading opendeps manifest: %v", manifestPath)
+		manifest := model.Parse(manifestPath)
+
+		if flagVendorCheck {
+			checkVendoredSpecs(manifestPath, manifest)
+		} else {
+			index, err := vendoring.Vendor(manifestPath, manifest)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			logrus.Infof("vendored %d dependencies to: %v", len(index.Dependencies), vendoring.GetVendorDir(manifestPath))
+		}
+	},
+}
+
+func init() {
+	vendorCmd.Flags().BoolVar(&flagVendorCheck, "check", false, "Report differences between vendored specs and their sources, without changing them")
+	rootCmd.AddCommand(vendorCmd)
+}
+
+func checkVendoredSpecs(manifestPath string, manifest *model.OpenDeps) {
+	// always revalidate cached content, so upstream changes are not hidden
+	fileutil.ConfigureCache(fileutil.CacheConfig{
+		Disabled: flagNoCache,
+		Offline:  flagOffline,
+	})
+
+	drift, err := vendoring.Check(manifestPath, manifest)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if len(drift) == 0 {
+		logrus.Infof("vendored specs are up to date")
+		return
+	}
+	for _, d := range drift {
+		logrus.Warnf("- %v", d)
+	}
+	logrus.Warnf("vendored specs differ from their sources - run 'opendeps vendor' to update them")
+	os.Exit(1)
+}
+
+// applyVendorOverlay reads vendored specs in place of their sources,
+// if the manifest has been vendored.
+func applyVendorOverlay(manifestPath string, manifest *model.OpenDeps) {
+	if err := vendoring.ApplyOverlay(manifestPath, manifest); err != nil {
+		logrus.Fatal(err)
+	}
+}
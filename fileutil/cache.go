@@ -0,0 +1,251 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutil
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const cacheEntryMetaSuffix = ".json"
+const cacheEntryBodySuffix = ".body"
+
+// CacheConfig controls how content fetched over HTTP(S) is cached.
+type CacheConfig struct {
+	// Disabled bypasses the cache entirely.
+	Disabled bool
+
+	// Offline serves content only from the cache, never from the network.
+	Offline bool
+
+	// TTL is the period for which a cache entry is used without revalidation.
+	TTL time.Duration
+}
+
+// CacheEntry describes a cached response for a URL.
+type CacheEntry struct {
+	Url          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Size         int64     `json:"-"`
+}
+
+var cacheConfig = CacheConfig{
+	TTL: time.Hour,
+}
+
+// ConfigureCache replaces the cache configuration used for
+// subsequent remote fetches.
+func ConfigureCache(config CacheConfig) {
+	cacheConfig = config
+}
+
+// GetCacheDir returns the directory in which fetched content is cached.
+func GetCacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine user cache dir: %v", err)
+	}
+	return filepath.Join(userCacheDir, "opendeps", "http"), nil
+}
+
+// fetchHttpCached serves the content of url from the cache if it is
+// fresh, otherwise revalidates or refreshes the cache entry.
+func fetchHttpCached(url string) (io.ReadCloser, error) {
+	cacheDir, err := GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	entryPath := filepath.Join(cacheDir, fmt.Sprintf("%x", sha256.Sum256([]byte(url))))
+	entry, err := readCacheEntry(entryPath)
+	if err != nil {
+		logrus.Warnf("ignoring unreadable cache entry for [%v]: %v", url, err)
+		entry = nil
+	}
+
+	if cacheConfig.Offline {
+		if entry == nil {
+			return nil, fmt.Errorf("offline mode: no cached content for URL [%v]\n", url)
+		}
+		logrus.Debugf("offline mode: serving %v from cache", url)
+		return os.Open(entryPath + cacheEntryBodySuffix)
+	}
+	if entry != nil && time.Since(entry.FetchedAt) < cacheConfig.TTL {
+		logrus.Debugf("serving %v from cache (fetched %v)", url, entry.FetchedAt.Format(time.RFC3339))
+		return os.Open(entryPath + cacheEntryBodySuffix)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request for URL [%v]: %v\n", url, err)
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if entry != nil {
+			logrus.Warnf("failed to revalidate [%v] - serving stale content from cache: %v", url, err)
+			return os.Open(entryPath + cacheEntryBodySuffix)
+		}
+		return nil, fmt.Errorf("failed to fetch from URL [%v]: %v\n", url, err)
+	}
+	defer resp.Body.Close()
+	logrus.Debugf("%v returned: %s\n", url, resp.Status)
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		entry.FetchedAt = time.Now()
+		if err := writeCacheEntryMeta(entryPath, entry); err != nil {
+			logrus.Warnf("failed to update cache entry for [%v]: %v", url, err)
+		}
+		return os.Open(entryPath + cacheEntryBodySuffix)
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to read from URL [%v]: %s\n", url, resp.Status)
+	}
+
+	entry = &CacheEntry{
+		Url:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	}
+	if err := writeCacheEntry(cacheDir, entryPath, entry, resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to cache content from URL [%v]: %v\n", url, err)
+	}
+	return os.Open(entryPath + cacheEntryBodySuffix)
+}
+
+func readCacheEntry(entryPath string) (*CacheEntry, error) {
+	raw, err := ioutil.ReadFile(entryPath + cacheEntryMetaSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entry := &CacheEntry{}
+	if err := json.Unmarshal(raw, entry); err != nil {
+		return nil, err
+	}
+	bodyInfo, err := os.Stat(entryPath + cacheEntryBodySuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entry.Size = bodyInfo.Size()
+	return entry, nil
+}
+
+func writeCacheEntry(cacheDir string, entryPath string, entry *CacheEntry, body io.Reader) error {
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a failed download
+	// does not replace a valid entry
+	tempFile, err := ioutil.TempFile(cacheDir, "download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = io.Copy(tempFile, body)
+	tempFile.Close()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile.Name(), entryPath+cacheEntryBodySuffix); err != nil {
+		return err
+	}
+	return writeCacheEntryMeta(entryPath, entry)
+}
+
+func writeCacheEntryMeta(entryPath string, entry *CacheEntry) error {
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(entryPath+cacheEntryMetaSuffix, meta, 0600)
+}
+
+// ListCacheEntries returns the entries in the cache, ordered by URL.
+func ListCacheEntries() ([]CacheEntry, error) {
+	cacheDir, err := GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading cache dir: %v: %v", cacheDir, err)
+	}
+
+	var entries []CacheEntry
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), cacheEntryMetaSuffix) {
+			continue
+		}
+		entryPath := filepath.Join(cacheDir, strings.TrimSuffix(file.Name(), cacheEntryMetaSuffix))
+		entry, err := readCacheEntry(entryPath)
+		if err != nil {
+			logrus.Warnf("skipping unreadable cache entry: %v: %v", entryPath, err)
+			continue
+		} else if entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Url < entries[j].Url
+	})
+	return entries, nil
+}
+
+// ClearCache removes all cached content, returning the number of entries removed.
+func ClearCache() (int, error) {
+	entries, err := ListCacheEntries()
+	if err != nil {
+		return 0, err
+	}
+	cacheDir, err := GetCacheDir()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return 0, fmt.Errorf("error removing cache dir: %v: %v", cacheDir, err)
+	}
+	return len(entries), nil
+}
@@ -17,12 +17,14 @@ limitations under the License.
 package fileutil
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -62,7 +64,13 @@ func CopyContent(source string, destFile string) error {
 func ReadContent(source string) (io.ReadCloser, error) {
 	var content io.ReadCloser
 	var err error
-	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+	if pinnedContent, found := pinned[normaliseLocation(source)]; found {
+		logrus.Debugf("reading pinned content of %v", source)
+		return ioutil.NopCloser(bytes.NewReader(pinnedContent)), nil
+	} else if overlayPath, found := overlays[normaliseLocation(source)]; found {
+		logrus.Debugf("reading %v from overlay: %v", source, overlayPath)
+		content, err = os.Open(overlayPath)
+	} else if IsRemote(source) {
 		content, err = fetchHttp(source)
 	} else {
 		content, err = fetchFile(source)
@@ -73,7 +81,31 @@ func ReadContent(source string) (io.ReadCloser, error) {
 	return content, err
 }
 
+// ReadAllContent reads the full content of a file, based on
+// its scheme, such as file:// or http://
+func ReadAllContent(source string) ([]byte, error) {
+	reader, err := ReadContent(source)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	raw, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from: %v: %v\n", source, err)
+	}
+	return raw, nil
+}
+
+// IsRemote determines whether the source should be fetched over HTTP(S).
+func IsRemote(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
 func fetchHttp(url string) (io.ReadCloser, error) {
+	if !cacheConfig.Disabled {
+		return fetchHttpCached(url)
+	}
 	resp, err := http.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch from URL [%v]: %v\n", url, err)
@@ -102,23 +134,49 @@ func writeToFile(source io.Reader, dest string) error {
 }
 
 func fetchFile(source string) (io.ReadCloser, error) {
-	var normalisedPath string
+	return os.Open(normaliseLocation(source))
+}
+
+// normaliseLocation strips the file scheme from local paths,
+// leaving remote URLs unchanged.
+func normaliseLocation(source string) string {
 	if strings.HasPrefix(source, "file://") {
-		normalisedPath = strings.TrimPrefix(source, "file://")
+		return strings.TrimPrefix(source, "file://")
 	} else if strings.HasPrefix(source, "file:") {
-		normalisedPath = strings.TrimPrefix(source, "file:")
-	} else {
-		normalisedPath = source
+		return strings.TrimPrefix(source, "file:")
+	}
+	return source
+}
+
+// ResolveLocation resolves ref relative to the location of the file
+// containing it, which may be a local path or a remote URL.
+func ResolveLocation(base string, ref string) (string, error) {
+	if IsRemote(ref) {
+		return ref, nil
+	} else if IsRemote(base) {
+		baseUrl, err := url.Parse(base)
+		if err != nil {
+			return "", fmt.Errorf("invalid URL: %v: %v", base, err)
+		}
+		refUrl, err := url.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("invalid reference: %v: %v", ref, err)
+		}
+		return baseUrl.ResolveReference(refUrl).String(), nil
+	}
+	refPath := normaliseLocation(ref)
+	if filepath.IsAbs(refPath) {
+		return refPath, nil
 	}
-	return os.Open(normalisedPath)
+	return filepath.Join(filepath.Dir(normaliseLocation(base)), filepath.FromSlash(refPath)), nil
 }
 
 func MakeAbsoluteRelativeToFile(inputPath string, relativeToPath string) string {
 	specDir := filepath.Dir(relativeToPath)
 
 	var normalisedPath string
-	if strings.HasPrefix(inputPath, "./") {
-		normalisedPath = filepath.Join(specDir, strings.TrimPrefix(inputPath, "."))
+	if strings.HasPrefix(inputPath, "./") || strings.HasPrefix(inputPath, "../") {
+		normalisedPath = filepath.Join(specDir, inputPath)
 	} else {
 		normalisedPath = inputPath
 	}
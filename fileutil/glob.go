@@ -0,0 +1,137 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutil
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FindOptions controls which files FindFiles returns.
+type FindOptions struct {
+	// Recursive searches subdirectories, other than hidden ones
+	Recursive bool
+
+	// Include, if not empty, lists glob patterns one of which files must match
+	Include []string
+
+	// Exclude lists glob patterns that files must not match
+	Exclude []string
+}
+
+// FindFiles returns the paths, relative to dir, of the files in dir with one
+// of the given extensions that satisfy the options, in lexical order.
+func FindFiles(dir string, extensions []string, options FindOptions) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if entry.IsDir() {
+			if relPath == "." {
+				return nil
+			}
+			if !options.Recursive || strings.HasPrefix(entry.Name(), ".") || matchesAny(options.Exclude, relPath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !hasExtension(relPath, extensions) {
+			return nil
+		}
+		if len(options.Include) > 0 && !matchesAny(options.Include, relPath) {
+			return nil
+		}
+		if matchesAny(options.Exclude, relPath) {
+			return nil
+		}
+		files = append(files, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search directory [%v]: %v", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func hasExtension(path string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob determines whether the slash-separated relative path matches the
+// glob pattern. '*' matches any characters other than '/', '?' matches one
+// such character, and '**' matches any number of directories. A pattern
+// without a '/' is matched against the base name of the path only.
+func MatchGlob(pattern string, relPath string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if !strings.Contains(pattern, "/") {
+		relPath = relPath[strings.LastIndex(relPath, "/")+1:]
+	}
+	matched, err := regexp.MatchString(globToRegexp(pattern), relPath)
+	return err == nil && matched
+}
+
+func globToRegexp(pattern string) string {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				expr.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return expr.String()
+}
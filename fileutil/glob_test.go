@@ -0,0 +1,117 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		relPath string
+		want    bool
+	}{
+		{name: "base name pattern matches at any depth", pattern: "*.yaml", relPath: "apis/core/pets.yaml", want: true},
+		{name: "base name pattern does not match other extension", pattern: "*.yaml", relPath: "apis/pets.json", want: false},
+		{name: "directory name pattern", pattern: "examples", relPath: "apis/examples", want: true},
+		{name: "star does not cross directories", pattern: "apis/*.yaml", relPath: "apis/core/pets.yaml", want: false},
+		{name: "star within directory", pattern: "apis/*.yaml", relPath: "apis/pets.yaml", want: true},
+		{name: "double star matches any directories", pattern: "apis/**/*.yaml", relPath: "apis/core/v1/pets.yaml", want: true},
+		{name: "double star matches no directories", pattern: "apis/**/*.yaml", relPath: "apis/pets.yaml", want: true},
+		{name: "leading double star", pattern: "**/test/**", relPath: "apis/test/pets.yaml", want: true},
+		{name: "trailing double star", pattern: "apis/**", relPath: "apis/core/pets.yaml", want: true},
+		{name: "question mark matches one character", pattern: "pets-v?.yaml", relPath: "pets-v2.yaml", want: true},
+		{name: "question mark does not match slash", pattern: "apis?pets.yaml", relPath: "apis/pets.yaml", want: false},
+		{name: "dot is literal", pattern: "pets.yaml", relPath: "petsxyaml", want: false},
+		{name: "leading dot slash is ignored", pattern: "./apis/*.yaml", relPath: "apis/pets.yaml", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchGlob(tt.pattern, tt.relPath); got != tt.want {
+				t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"pets.yaml",
+		"stock.JSON",
+		"notes.txt",
+		"apis/core/orders.yaml",
+		"apis/examples/sample.yaml",
+		".hidden/secret.yaml",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		options FindOptions
+		want    []string
+	}{
+		{
+			name: "top-level only by default",
+			want: []string{"pets.yaml", "stock.JSON"},
+		},
+		{
+			name:    "recursive skips hidden directories",
+			options: FindOptions{Recursive: true},
+			want:    []string{"apis/core/orders.yaml", "apis/examples/sample.yaml", "pets.yaml", "stock.JSON"},
+		},
+		{
+			name:    "include",
+			options: FindOptions{Recursive: true, Include: []string{"apis/**"}},
+			want:    []string{"apis/core/orders.yaml", "apis/examples/sample.yaml"},
+		},
+		{
+			name:    "exclude directory by name",
+			options: FindOptions{Recursive: true, Exclude: []string{"examples"}},
+			want:    []string{"apis/core/orders.yaml", "pets.yaml", "stock.JSON"},
+		},
+		{
+			name:    "exclude file",
+			options: FindOptions{Exclude: []string{"stock.*"}},
+			want:    []string{"pets.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindFiles(dir, []string{".yaml", ".json"}, tt.options)
+			if err != nil {
+				t.Fatalf("FindFiles() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
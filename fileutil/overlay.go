@@ -0,0 +1,40 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutil
+
+import "github.com/sirupsen/logrus"
+
+// overlays maps source locations to local files that are read in their place.
+var overlays = make(map[string]string)
+
+// pinned maps source locations to content that is read in their place.
+var pinned = make(map[string][]byte)
+
+// RegisterOverlay causes subsequent reads of source to be served
+// from the local file at overlayPath instead.
+func RegisterOverlay(source string, overlayPath string) {
+	logrus.Tracef("registered overlay for %v: %v", source, overlayPath)
+	overlays[normaliseLocation(source)] = overlayPath
+}
+
+// PinContent causes subsequent reads of source to be served the content,
+// such as content that has been verified against a digest, rather than
+// fetching it again.
+func PinContent(source string, content []byte) {
+	logrus.Tracef("pinned content of %v", source)
+	pinned[normaliseLocation(source)] = content
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphqlspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// CheckTypename sends a '{ __typename }' query
+	CheckTypename = "typename"
+
+	// CheckIntrospection sends an introspection query for the name of the query type
+	CheckIntrospection = "introspection"
+)
+
+var checkQueries = map[string]string{
+	CheckTypename:      "{ __typename }",
+	CheckIntrospection: "{ __schema { queryType { name } } }",
+}
+
+type graphqlResponse struct {
+	Data   map[string]interface{}
+	Errors []struct {
+		Message string
+	}
+}
+
+// Check sends the query for the check to the GraphQL endpoint, and checks
+// the response holds data and no errors.
+func Check(endpoint string, check string) error {
+	query, found := checkQueries[check]
+	if !found {
+		return fmt.Errorf("unsupported GraphQL availability check: %v", check)
+	}
+	body, _ := json.Marshal(map[string]string{"query": query})
+	resp, err := http.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to reach GraphQL endpoint [%v]: %v", endpoint, err)
+	}
+	defer resp.Body.Close()
+	logrus.Debugf("checked GraphQL endpoint [%v]: %s", endpoint, resp.Status)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to reach GraphQL endpoint [%v]: %s", endpoint, resp.Status)
+	}
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from GraphQL endpoint [%v]: %v", endpoint, err)
+	}
+	result := graphqlResponse{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return fmt.Errorf("invalid response from GraphQL endpoint [%v]: %v", endpoint, err)
+	}
+	if len(result.Errors) > 0 {
+		var messages []string
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("GraphQL endpoint [%v] returned errors: %v", endpoint, strings.Join(messages, "; "))
+	}
+	if len(result.Data) == 0 {
+		return fmt.Errorf("GraphQL endpoint [%v] returned no data", endpoint)
+	}
+	return nil
+}
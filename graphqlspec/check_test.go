@@ -0,0 +1,109 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphqlspec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		check      string
+		statusCode int
+		response   string
+		wantQuery  string
+		wantErr    bool
+	}{
+		{
+			name:       "typename",
+			check:      CheckTypename,
+			statusCode: http.StatusOK,
+			response:   `{"data": {"__typename": "Query"}}`,
+			wantQuery:  "{ __typename }",
+		},
+		{
+			name:       "introspection",
+			check:      CheckIntrospection,
+			statusCode: http.StatusOK,
+			response:   `{"data": {"__schema": {"queryType": {"name": "Query"}}}}`,
+			wantQuery:  "{ __schema { queryType { name } } }",
+		},
+		{
+			name:       "errors",
+			check:      CheckTypename,
+			statusCode: http.StatusOK,
+			response:   `{"errors": [{"message": "not allowed"}]}`,
+			wantQuery:  "{ __typename }",
+			wantErr:    true,
+		},
+		{
+			name:       "no data",
+			check:      CheckTypename,
+			statusCode: http.StatusOK,
+			response:   `{"data": {}}`,
+			wantQuery:  "{ __typename }",
+			wantErr:    true,
+		},
+		{
+			name:       "non-2xx status",
+			check:      CheckTypename,
+			statusCode: http.StatusServiceUnavailable,
+			response:   `{"data": {"__typename": "Query"}}`,
+			wantQuery:  "{ __typename }",
+			wantErr:    true,
+		},
+		{
+			name:       "invalid response",
+			check:      CheckTypename,
+			statusCode: http.StatusOK,
+			response:   "<html></html>",
+			wantQuery:  "{ __typename }",
+			wantErr:    true,
+		},
+		{
+			name:    "unsupported check",
+			check:   "ping",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := map[string]string{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("invalid request body: %v", err)
+				}
+				query = body["query"]
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			if err := Check(server.URL, tt.check); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("Check() sent query %q, want %q", query, tt.wantQuery)
+			}
+		})
+	}
+}
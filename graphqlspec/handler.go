@@ -0,0 +1,167 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphqlspec
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"path/filepath"
+	"strings"
+)
+
+// Handler handles GraphQL schemas. They are not mocked.
+var Handler openapi.SpecHandler = graphqlHandler{}
+
+type graphqlHandler struct{}
+
+func (graphqlHandler) Kind() string {
+	return "GraphQL"
+}
+
+func (graphqlHandler) Detect(location string, _ []byte) bool {
+	return IsGraphQLSpec(location)
+}
+
+func (graphqlHandler) DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error) {
+	return DiscoverSpecs(dir, options)
+}
+
+// Scaffold returns a dependency summarised by the first line of the
+// description of the schema, and described by the rest.
+func (graphqlHandler) Scaffold(location string, raw []byte) (*model.Dependency, error) {
+	spec, err := ParseContent(location, raw)
+	if err != nil {
+		return nil, err
+	}
+	dep := &model.Dependency{}
+	lines := strings.SplitN(strings.TrimSpace(spec.Description), "\n", 2)
+	dep.Summary = strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		dep.Description = strings.TrimSpace(lines[1])
+	}
+	return dep, nil
+}
+
+// FindExternalRefs returns no references, since GraphQL schemas have none.
+func (graphqlHandler) FindExternalRefs(string, []byte) ([]string, error) {
+	return nil, nil
+}
+
+// Check sends a query to the endpoint of the dependency, then checks
+// its deployed version.
+func (graphqlHandler) Check(dep openapi.DependencyContext) error {
+	endpoint, err := determineEndpoint(dep)
+	if err != nil {
+		return err
+	}
+	check := CheckTypename
+	if dep.Dependency.Availability != nil && dep.Dependency.Availability.Check != "" {
+		check = dep.Dependency.Availability.Check
+	}
+	if err := Check(endpoint, check); err != nil {
+		return err
+	}
+	return openapi.CheckDeployedVersion(dep, nil)
+}
+
+// determineEndpoint returns the availability path of the dependency,
+// relative to the server URL override, if set, otherwise its
+// availability URL.
+func determineEndpoint(dep openapi.DependencyContext) (string, error) {
+	var availability model.Availability
+	if dep.Dependency.Availability != nil {
+		availability = *dep.Dependency.Availability
+	}
+	if dep.ServerUrl != "" {
+		if availability.Path == "" {
+			return dep.ServerUrl, nil
+		}
+		return strings.TrimSuffix(dep.ServerUrl, "/") + "/" + strings.TrimPrefix(availability.Path, "/"), nil
+	}
+	if availability.Url != "" {
+		return availability.Url, nil
+	}
+	return "", fmt.Errorf("no endpoint for GraphQL dependency %v - set its availability URL, an environment server or a --server override", dep.Name)
+}
+
+// Validate checks that the query documents of the dependency
+// are valid against its schema.
+func (graphqlHandler) Validate(dep openapi.DependencyContext) []openapi.Problem {
+	spec, err := Parse(dep.SpecPath)
+	if err != nil {
+		return []openapi.Problem{openapi.Problemf("", "spec could not be read: %v", err)}
+	}
+	logrus.Debugf("dependency [%v] spec is GraphQL with %d type(s)", dep.Name, len(spec.Schema.Types))
+
+	queryDocuments, problems := findQueryDocuments(dep)
+	for _, queryDocument := range queryDocuments {
+		raw, err := fileutil.ReadAllContent(queryDocument.Path)
+		if err != nil {
+			problems = append(problems, openapi.Problemf(queryDocument.Pointer, "query document could not be read: %v", err))
+			continue
+		}
+		for _, problem := range spec.ValidateQueries(queryDocument.Path, raw) {
+			problems = append(problems, openapi.Problemf(queryDocument.Pointer, "query is invalid: %v", problem))
+		}
+	}
+	if availability := dep.Dependency.Availability; availability != nil && availability.Check != "" && availability.Check != CheckTypename && availability.Check != CheckIntrospection {
+		problems = append(problems, openapi.Problemf("/availability/x-check", "availability check [%v] is not one of: %v, %v", availability.Check, CheckTypename, CheckIntrospection))
+	}
+	return append(problems, openapi.ValidateVersion(dep, "", nil)...)
+}
+
+// queryDocument is a query document of a dependency, with the
+// pointer to the entry in its x-queries that matched it.
+type queryDocument struct {
+	Path    string
+	Pointer string
+}
+
+// findQueryDocuments returns the query documents of the dependency,
+// and the problems finding them. Local paths, relative to the
+// manifest, may be glob patterns.
+func findQueryDocuments(dep openapi.DependencyContext) ([]queryDocument, []openapi.Problem) {
+	var documents []queryDocument
+	var problems []openapi.Problem
+	for i, query := range dep.Dependency.Queries {
+		pointer := fmt.Sprintf("/x-queries/%d", i)
+		queryPath := fileutil.MakeAbsoluteRelativeToFile(query, dep.ManifestPath)
+		if fileutil.IsRemote(queryPath) {
+			documents = append(documents, queryDocument{Path: queryPath, Pointer: pointer})
+			continue
+		}
+		matches, err := filepath.Glob(queryPath)
+		if err != nil || len(matches) == 0 {
+			problems = append(problems, openapi.Problemf(pointer, "query document not found: %v", query))
+			continue
+		}
+		for _, match := range matches {
+			documents = append(documents, queryDocument{Path: match, Pointer: pointer})
+		}
+	}
+	return documents, problems
+}
+
+func (graphqlHandler) StartMock(deps []openapi.DependencyContext, _ openapi.MockOptions) (openapi.Mock, error) {
+	for _, dep := range deps {
+		logrus.Warnf("skipping mock for %v - GraphQL specs are not mocked: %v", dep.Name, dep.SpecPath)
+	}
+	return nil, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphqlspec
+
+import (
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `"""
+Orders API
+"""
+schema {
+  query: Query
+}
+
+type Query {
+  order(id: ID!): Order
+}
+
+type Order {
+  id: ID!
+  quantity: Int
+}
+`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		queries    map[string]string
+		dependency model.Dependency
+		want       []openapi.Problem
+	}{
+		{
+			name:       "valid query documents",
+			queries:    map[string]string{"queries/order.graphql": "query Order { order(id: \"1\") { id quantity } }"},
+			dependency: model.Dependency{Queries: []string{"./queries/*.graphql"}},
+		},
+		{
+			name:       "invalid query document",
+			queries:    map[string]string{"queries/order.graphql": "query Order {\n  order(id: \"1\") { price }\n}"},
+			dependency: model.Dependency{Queries: []string{"./queries/*.graphql"}},
+			want: []openapi.Problem{
+				{Pointer: "/x-queries/0", Message: "query is invalid: queries/order.graphql:2:20: Cannot query field \"price\" on type \"Order\"."},
+			},
+		},
+		{
+			name:       "glob with no matches",
+			dependency: model.Dependency{Queries: []string{"./queries/*.graphql"}},
+			want: []openapi.Problem{
+				{Pointer: "/x-queries/0", Message: "query document not found: ./queries/*.graphql"},
+			},
+		},
+		{
+			name:       "supported availability check",
+			dependency: model.Dependency{Availability: &model.Availability{Check: CheckIntrospection}},
+		},
+		{
+			name:       "unsupported availability check",
+			dependency: model.Dependency{Availability: &model.Availability{Check: "ping"}},
+			want: []openapi.Problem{
+				{Pointer: "/availability/x-check", Message: "availability check [ping] is not one of: typename, introspection"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{"schema.graphql": testSchema}
+			for name, content := range tt.queries {
+				files[name] = content
+			}
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dep := openapi.DependencyContext{
+				Name:         "orders",
+				Dependency:   tt.dependency,
+				SpecPath:     filepath.Join(dir, "schema.graphql"),
+				ManifestPath: filepath.Join(dir, "opendeps.yaml"),
+			}
+
+			// query documents are reported by their path, which is in the temporary directory
+			var got []openapi.Problem
+			for _, problem := range Handler.Validate(dep) {
+				problem.Message = strings.ReplaceAll(problem.Message, dir+string(filepath.Separator), "")
+				problem.Message = strings.ReplaceAll(problem.Message, string(filepath.Separator), "/")
+				got = append(got, problem)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Validate() problem %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDetermineEndpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		availability *model.Availability
+		serverUrl    string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:         "availability URL",
+			availability: &model.Availability{Url: "https://example.com/graphql"},
+			want:         "https://example.com/graphql",
+		},
+		{
+			name:      "server URL override",
+			serverUrl: "http://localhost:8080",
+			want:      "http://localhost:8080",
+		},
+		{
+			name:         "path relative to server URL override",
+			availability: &model.Availability{Url: "https://example.com/graphql", Path: "/graphql"},
+			serverUrl:    "http://localhost:8080/",
+			want:         "http://localhost:8080/graphql",
+		},
+		{
+			name:         "path without server URL",
+			availability: &model.Availability{Path: "/graphql"},
+			wantErr:      true,
+		},
+		{
+			name:    "no endpoint",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dep := openapi.DependencyContext{
+				Name:       "orders",
+				Dependency: model.Dependency{Availability: tt.availability},
+				ServerUrl:  tt.serverUrl,
+			}
+			got, err := determineEndpoint(dep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("determineEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("determineEndpoint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphqlspec
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"opendeps.org/opendeps/fileutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var extensions = []string{".graphql", ".graphqls", ".gql"}
+
+// PartialModel holds a GraphQL schema, loaded from its SDL.
+type PartialModel struct {
+	Description string
+	Schema      *ast.Schema
+}
+
+// IsGraphQLSpec determines, from its file extension, whether the spec
+// is a GraphQL SDL file.
+func IsGraphQLSpec(specPath string) bool {
+	ext := strings.ToLower(path.Ext(strings.SplitN(specPath, "?", 2)[0]))
+	for _, graphqlExt := range extensions {
+		if ext == graphqlExt {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGraphQLSchema determines whether the raw content is a GraphQL schema,
+// rather than, for example, a document of queries.
+func IsGraphQLSchema(raw []byte) bool {
+	doc, err := parser.ParseSchema(&ast.Source{Input: string(raw)})
+	return err == nil && (len(doc.Definitions) > 0 || len(doc.Schema) > 0)
+}
+
+// Parse loads the GraphQL schema at the given path or URL.
+func Parse(specPath string) (*PartialModel, error) {
+	raw, err := fileutil.ReadAllContent(specPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseContent(specPath, raw)
+}
+
+// ParseContent loads the GraphQL schema at the given path or URL,
+// whose content is raw.
+func ParseContent(specPath string, raw []byte) (*PartialModel, error) {
+	source := &ast.Source{Name: specPath, Input: string(raw)}
+	schema, gqlErr := gqlparser.LoadSchema(source)
+	if gqlErr != nil {
+		return nil, fmt.Errorf("error loading GraphQL schema [%v]: %v", specPath, gqlErr)
+	}
+
+	o := &PartialModel{Schema: schema}
+	if doc, err := parser.ParseSchema(source); err == nil {
+		for _, definition := range doc.Schema {
+			if definition.Description != "" {
+				o.Description = definition.Description
+				break
+			}
+		}
+	}
+	logrus.Tracef("graphql schema parsed: %d type(s) from %v", len(schema.Types), specPath)
+	return o, nil
+}
+
+// QueryFields returns the names of the fields of the query type, in lexical order.
+func (o *PartialModel) QueryFields() []string {
+	var names []string
+	if o.Schema.Query != nil {
+		for _, field := range o.Schema.Query.Fields {
+			if !strings.HasPrefix(field.Name, "__") {
+				names = append(names, field.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateQueries validates the operations in the query document against
+// the schema, returning a description of each error, including its location.
+func (o *PartialModel) ValidateQueries(name string, raw []byte) []string {
+	_, errs := gqlparser.LoadQuery(o.Schema, string(raw))
+	var problems []string
+	for _, err := range errs {
+		location := name
+		if len(err.Locations) > 0 {
+			location = fmt.Sprintf("%v:%d:%d", name, err.Locations[0].Line, err.Locations[0].Column)
+		}
+		problems = append(problems, fmt.Sprintf("%v: %v", location, err.Message))
+	}
+	return problems
+}
+
+// DiscoverSpecs returns the paths of the GraphQL schemas in dir
+// that satisfy the options. Query documents are skipped.
+func DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error) {
+	files, err := fileutil.FindFiles(dir, extensions, options)
+	if err != nil {
+		return nil, err
+	}
+	var specs []string
+	for _, file := range files {
+		specPath := filepath.Join(dir, filepath.FromSlash(file))
+		raw, err := fileutil.ReadAllContent(specPath)
+		if err != nil {
+			return nil, err
+		}
+		if IsGraphQLSchema(raw) {
+			specs = append(specs, specPath)
+		} else {
+			logrus.Tracef("skipping file that is not a GraphQL schema: %v", specPath)
+		}
+	}
+	return specs, nil
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graphqlspec
+
+import "testing"
+
+func TestIsGraphQLSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want bool
+	}{
+		{name: "schema", raw: testSchema, want: true},
+		{name: "type definitions only", raw: "type Order { id: ID! }", want: true},
+		{name: "query document", raw: "query Order { order(id: \"1\") { id } }", want: false},
+		{name: "anonymous query", raw: "{ __typename }", want: false},
+		{name: "empty", raw: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGraphQLSchema([]byte(tt.raw)); got != tt.want {
+				t.Errorf("IsGraphQLSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcspec
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const checkTimeout = 5 * time.Second
+
+const (
+	// CheckHealth uses the gRPC health checking protocol
+	CheckHealth = "health"
+
+	// CheckReflection uses server reflection to list the server's services
+	CheckReflection = "reflection"
+)
+
+// Target is the address of a gRPC server.
+type Target struct {
+	Address string
+	TLS     bool
+}
+
+// ParseTarget determines the address of a gRPC server from a URL of the form
+// 'host:port' or 'scheme://host:port'. TLS is used for the grpcs and https schemes.
+func ParseTarget(serverUrl string) (*Target, error) {
+	if !strings.Contains(serverUrl, "://") {
+		return &Target{Address: strings.TrimSuffix(serverUrl, "/")}, nil
+	}
+	parsed, err := url.Parse(serverUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gRPC server URL [%v]: %v", serverUrl, err)
+	}
+	target := &Target{Address: parsed.Host}
+	switch strings.ToLower(parsed.Scheme) {
+	case "grpcs", "https":
+		target.TLS = true
+		if parsed.Port() == "" {
+			target.Address += ":443"
+		}
+	case "grpc", "http":
+		if parsed.Port() == "" {
+			target.Address += ":80"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme in gRPC server URL [%v]", serverUrl)
+	}
+	return target, nil
+}
+
+func (t *Target) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if t.TLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.DialContext(ctx, t.Address, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gRPC server [%v]: %v", t.Address, err)
+	}
+	return conn, nil
+}
+
+// CheckHealth calls the Check method of the server's health service, for
+// the given service name, or the server as a whole if it is empty, and
+// checks the status is SERVING.
+func (t *Target) CheckHealth(service string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+	if err != nil {
+		return fmt.Errorf("health check of gRPC server [%v] failed: %v", t.Address, err)
+	}
+	logrus.Debugf("health of gRPC server [%v] service [%v]: %v", t.Address, service, resp.GetStatus())
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("gRPC server [%v] service [%v] is %v", t.Address, service, resp.GetStatus())
+	}
+	return nil
+}
+
+// CheckReflection lists the services of the server using server reflection,
+// and checks that each of the given services is among them.
+func (t *Target) CheckReflection(services []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := grpcreflect.NewClient(ctx, rpb.NewServerReflectionClient(conn))
+	defer client.Reset()
+	served, err := client.ListServices()
+	if err != nil {
+		return fmt.Errorf("listing services of gRPC server [%v] failed: %v", t.Address, err)
+	}
+	logrus.Debugf("gRPC server [%v] serves: %v", t.Address, served)
+
+	available := make(map[string]bool)
+	for _, service := range served {
+		available[service] = true
+	}
+	for _, name := range services {
+		if service, _ := SplitMethod(name); !available[service] {
+			return fmt.Errorf("gRPC server [%v] does not serve %v", t.Address, service)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcspec
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"path/filepath"
+	"regexp"
+	k8syaml "sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+// Handler handles .proto files and descriptor sets. They are mocked by
+// a native gRPC server, rather than Imposter.
+var Handler openapi.SpecHandler = grpcHandler{}
+
+var servicePattern = regexp.MustCompile(`(?m)^\s*service\s+\w+\s*\{`)
+
+type grpcHandler struct{}
+
+func (grpcHandler) Kind() string {
+	return "gRPC"
+}
+
+func (grpcHandler) Detect(location string, _ []byte) bool {
+	return IsGrpcSpec(location)
+}
+
+// DiscoverSpecs returns the paths of the .proto files that define a service.
+func (grpcHandler) DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error) {
+	files, err := fileutil.FindFiles(dir, []string{".proto"}, options)
+	if err != nil {
+		return nil, err
+	}
+	var specs []string
+	for _, file := range files {
+		specPath := filepath.Join(dir, filepath.FromSlash(file))
+		raw, err := fileutil.ReadAllContent(specPath)
+		if err != nil {
+			return nil, err
+		}
+		if servicePattern.Match(raw) {
+			specs = append(specs, specPath)
+		}
+	}
+	return specs, nil
+}
+
+// Scaffold returns a dependency using all of the services in the spec,
+// summarised by the name of its service, if it has only one, or its package.
+func (grpcHandler) Scaffold(location string, raw []byte) (*model.Dependency, error) {
+	spec, err := ParseContent(location, raw)
+	if err != nil {
+		return nil, err
+	}
+	dep := &model.Dependency{
+		Summary:  spec.Package,
+		Services: spec.ServiceNames(),
+	}
+	if len(spec.Services) == 1 {
+		dep.Summary = spec.Services[0].GetName()
+	}
+	return dep, nil
+}
+
+// FindExternalRefs returns the imports of a .proto file. Descriptor
+// sets are self-contained.
+func (grpcHandler) FindExternalRefs(location string, raw []byte) ([]string, error) {
+	if strings.EqualFold(filepath.Ext(strings.SplitN(location, "?", 2)[0]), ".proto") {
+		return FindImports(raw), nil
+	}
+	return nil, nil
+}
+
+// Check checks the availability of the dependency using the health
+// checking protocol or server reflection, then its deployed version.
+func (grpcHandler) Check(dep openapi.DependencyContext) error {
+	serverUrl, found := dep.ServerUrlOverride()
+	if !found {
+		return fmt.Errorf("no server for gRPC dependency %v - set its availability URL, an environment server or a --server override", dep.Name)
+	}
+	target, err := ParseTarget(serverUrl)
+	if err != nil {
+		return err
+	}
+
+	availability := dep.Dependency.Availability
+	check := CheckHealth
+	if availability != nil && availability.Check != "" {
+		check = availability.Check
+	}
+	switch check {
+	case CheckHealth:
+		var service string
+		if availability != nil {
+			service = strings.TrimPrefix(availability.Path, "/")
+		}
+		err = target.CheckHealth(service)
+	case CheckReflection:
+		err = target.CheckReflection(dep.Dependency.Services)
+	default:
+		err = fmt.Errorf("unsupported availability check for %v: %v", dep.Name, check)
+	}
+	if err != nil {
+		return err
+	}
+	return openapi.CheckDeployedVersion(dep, nil)
+}
+
+// Validate checks that the services, methods and examples
+// of the dependency are defined in its spec.
+func (grpcHandler) Validate(dep openapi.DependencyContext) []openapi.Problem {
+	spec, err := Parse(dep.SpecPath)
+	if err != nil {
+		return []openapi.Problem{openapi.Problemf("", "spec could not be read: %v", err)}
+	}
+	logrus.Debugf("dependency [%v] spec is gRPC with %d service(s)", dep.Name, len(spec.Services))
+
+	var problems []openapi.Problem
+	for i, name := range dep.Dependency.Services {
+		if !spec.Has(name) {
+			problems = append(problems, openapi.Problemf(fmt.Sprintf("/x-services/%d", i), "service or method [%v] is not defined in its spec", name))
+		}
+	}
+	if _, err := buildExamples(dep.Dependency); err != nil {
+		problems = append(problems, openapi.Problemf("/x-examples", "examples are invalid: %v", err))
+	} else {
+		var methods []string
+		for method := range dep.Dependency.Examples {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			if spec.FindMethod(method) == nil {
+				problems = append(problems, openapi.Problemf("/x-examples/"+strings.ReplaceAll(strings.ReplaceAll(method, "~", "~0"), "/", "~1"), "example method [%v] is not defined in its spec", method))
+			}
+		}
+	}
+	if availability := dep.Dependency.Availability; availability != nil && availability.Check != "" && availability.Check != CheckHealth && availability.Check != CheckReflection {
+		problems = append(problems, openapi.Problemf("/availability/x-check", "availability check [%v] is not one of: %v, %v", availability.Check, CheckHealth, CheckReflection))
+	}
+	return append(problems, openapi.ValidateVersion(dep, "", nil)...)
+}
+
+// StartMock serves mocks of the services of the dependencies
+// on the gRPC port.
+func (grpcHandler) StartMock(deps []openapi.DependencyContext, options openapi.MockOptions) (openapi.Mock, error) {
+	mock := NewMockServer()
+	for _, dep := range deps {
+		spec, err := Parse(dep.SpecPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse spec for %v: %v", dep.Name, err)
+		}
+		examples, err := buildExamples(dep.Dependency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid examples for %v: %v", dep.Name, err)
+		}
+		if err := mock.Register(spec, examples); err != nil {
+			return nil, fmt.Errorf("failed to mock %v: %v", dep.Name, err)
+		}
+		logrus.Debugf("mocking gRPC services of %v: %v", dep.Name, spec.ServiceNames())
+	}
+	if err := mock.Start(options.GrpcPort); err != nil {
+		return nil, err
+	}
+	return mock, nil
+}
+
+// buildExamples converts the examples of the dependency to JSON.
+func buildExamples(dep model.Dependency) (map[string][]byte, error) {
+	examples := make(map[string][]byte)
+	for method, example := range dep.Examples {
+		content, err := yaml.Marshal(example)
+		if err != nil {
+			return nil, err
+		}
+		json, err := k8syaml.YAMLToJSON(content)
+		if err != nil {
+			return nil, fmt.Errorf("example for method %v: %v", method, err)
+		}
+		examples[method] = json
+	}
+	return examples, nil
+}
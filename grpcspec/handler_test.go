@@ -0,0 +1,84 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcspec
+
+import (
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/openapi"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		dependency model.Dependency
+		want       []openapi.Problem
+	}{
+		{
+			name: "services, methods and examples defined",
+			dependency: model.Dependency{
+				Services: []string{"shop.Orders", "shop.Orders/GetOrder"},
+				Examples: map[string]interface{}{"shop.Orders/GetOrder": map[string]interface{}{"id": "123"}},
+			},
+		},
+		{
+			name:       "service and method missing from spec",
+			dependency: model.Dependency{Services: []string{"shop.Orders", "shop.Payments", "shop.Orders/CancelOrder"}},
+			want: []openapi.Problem{
+				{Pointer: "/x-services/1", Message: "service or method [shop.Payments] is not defined in its spec"},
+				{Pointer: "/x-services/2", Message: "service or method [shop.Orders/CancelOrder] is not defined in its spec"},
+			},
+		},
+		{
+			name: "example method missing from spec",
+			dependency: model.Dependency{
+				Examples: map[string]interface{}{"shop.Orders/CancelOrder": map[string]interface{}{}},
+			},
+			want: []openapi.Problem{
+				{Pointer: "/x-examples/shop.Orders~1CancelOrder", Message: "example method [shop.Orders/CancelOrder] is not defined in its spec"},
+			},
+		},
+		{
+			name:       "supported availability check",
+			dependency: model.Dependency{Availability: &model.Availability{Check: CheckReflection}},
+		},
+		{
+			name:       "unsupported availability check",
+			dependency: model.Dependency{Availability: &model.Availability{Check: "ping"}},
+			want: []openapi.Problem{
+				{Pointer: "/availability/x-check", Message: "availability check [ping] is not one of: health, reflection"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dep := openapi.DependencyContext{Name: "orders", Dependency: tt.dependency, SpecPath: writeProto(t, testProto)}
+			if got := Handler.Validate(dep); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_unreadableSpec(t *testing.T) {
+	dep := openapi.DependencyContext{Name: "orders", SpecPath: writeProto(t, "syntax = \"proto3\";\nservice {")}
+	got := Handler.Validate(dep)
+	if len(got) != 1 || got[0].Pointer != "" {
+		t.Errorf("Validate() = %+v, want one problem with the spec", got)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcspec
+
+import (
+	"fmt"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+)
+
+// MockServer is a gRPC server that responds to each method of the services
+// registered with it with an example message, if one is configured, or the
+// default (empty) message of the method's output type. It also serves the
+// gRPC health checking protocol, reporting each service as SERVING.
+type MockServer struct {
+	server   *grpc.Server
+	health   *health.Server
+	methods  map[string]*desc.MethodDescriptor
+	examples map[string][]byte
+	done     chan struct{}
+}
+
+func NewMockServer() *MockServer {
+	m := &MockServer{
+		health:   health.NewServer(),
+		methods:  make(map[string]*desc.MethodDescriptor),
+		examples: make(map[string][]byte),
+		done:     make(chan struct{}),
+	}
+	m.server = grpc.NewServer(grpc.UnknownServiceHandler(m.handle))
+	grpc_health_v1.RegisterHealthServer(m.server, m.health)
+	return m
+}
+
+// Register adds the services of the spec to the mock. Examples are the
+// JSON representations of response messages, keyed by method name,
+// in the form 'package.Service/Method'.
+func (m *MockServer) Register(spec *PartialModel, examples map[string][]byte) error {
+	for _, service := range spec.Services {
+		for _, method := range service.GetMethods() {
+			fullMethod := service.GetFullyQualifiedName() + "/" + method.GetName()
+			m.methods[fullMethod] = method
+		}
+		m.health.SetServingStatus(service.GetFullyQualifiedName(), grpc_health_v1.HealthCheckResponse_SERVING)
+	}
+	for fullMethod, example := range examples {
+		method := spec.FindMethod(fullMethod)
+		if method == nil {
+			return fmt.Errorf("example given for unknown method: %v", fullMethod)
+		}
+		if err := dynamic.NewMessage(method.GetOutputType()).UnmarshalJSON(example); err != nil {
+			return fmt.Errorf("invalid example for method %v: %v", fullMethod, err)
+		}
+		_, methodName := SplitMethod(fullMethod)
+		m.examples[method.GetService().GetFullyQualifiedName()+"/"+methodName] = example
+	}
+	return nil
+}
+
+// Start listens on the port, then serves requests in the background
+// until Stop is called or serving fails, when Done is closed.
+func (m *MockServer) Start(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %v", port, err)
+	}
+	logrus.Infof("gRPC mock listening on port %d", port)
+	go func() {
+		defer close(m.done)
+		if err := m.server.Serve(listener); err != nil {
+			logrus.Errorf("gRPC mock stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (m *MockServer) Stop() {
+	m.server.Stop()
+}
+
+func (m *MockServer) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *MockServer) handle(_ interface{}, stream grpc.ServerStream) error {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	serviceName, methodName := SplitMethod(fullMethod)
+	method, found := m.methods[serviceName+"/"+methodName]
+	if !found {
+		return status.Errorf(codes.Unimplemented, "method %v is not mocked", fullMethod)
+	}
+	logrus.Debugf("gRPC mock received call to %v", fullMethod)
+
+	// consume the request(s), whether unary or streaming
+	for {
+		req := dynamic.NewMessage(method.GetInputType())
+		if err := stream.RecvMsg(req); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if !method.IsClientStreaming() {
+			break
+		}
+	}
+
+	resp := dynamic.NewMessage(method.GetOutputType())
+	if example, found := m.examples[serviceName+"/"+methodName]; found {
+		if err := resp.UnmarshalJSON(example); err != nil {
+			return status.Errorf(codes.Internal, "invalid example for method %v: %v", fullMethod, err)
+		}
+	}
+	return stream.SendMsg(resp)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcspec
+
+import (
+	"context"
+	"fmt"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMockServer(t *testing.T) {
+	spec, err := Parse(writeProto(t, testProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := NewMockServer()
+	examples := map[string][]byte{"shop.Orders/GetOrder": []byte(`{"id": "123", "quantity": 2}`)}
+	if err := mock.Register(spec, examples); err != nil {
+		t.Fatal(err)
+	}
+	port := freePort(t)
+	if err := mock.Start(port); err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Stop()
+
+	target := &Target{Address: fmt.Sprintf("localhost:%d", port)}
+	if err := target.CheckHealth("shop.Orders"); err != nil {
+		t.Errorf("CheckHealth() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, target.Address, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	stub := grpcdynamic.NewStub(conn)
+
+	method := spec.FindMethod("shop.Orders/GetOrder")
+	req := dynamic.NewMessage(method.GetInputType())
+	req.SetFieldByName("id", "123")
+	resp, err := stub.InvokeRpc(ctx, method, req)
+	if err != nil {
+		t.Fatalf("InvokeRpc() error = %v", err)
+	}
+	order, err := dynamic.AsDynamicMessage(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, quantity := order.GetFieldByName("id"), order.GetFieldByName("quantity"); id != "123" || quantity != int32(2) {
+		t.Errorf("GetOrder response = %v, %v, want 123, 2", id, quantity)
+	}
+
+	// methods without examples respond with the default message
+	stream, err := stub.InvokeRpcServerStream(ctx, spec.FindMethod("shop.Orders/WatchOrders"), req)
+	if err != nil {
+		t.Fatalf("InvokeRpcServerStream() error = %v", err)
+	}
+	resp, err = stream.RecvMsg()
+	if err != nil {
+		t.Fatalf("RecvMsg() error = %v", err)
+	}
+	if order, _ := dynamic.AsDynamicMessage(resp); order.GetFieldByName("id") != "" {
+		t.Errorf("WatchOrders response = %v, want default message", order)
+	}
+
+	// methods not in the spec are unimplemented
+	err = conn.Invoke(ctx, "/shop.Orders/CancelOrder", req, dynamic.NewMessage(method.GetOutputType()))
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("CancelOrder error = %v, want code %v", err, codes.Unimplemented)
+	}
+
+	mock.Stop()
+	select {
+	case <-mock.Done():
+	case <-time.After(5 * time.Second):
+		t.Errorf("Done() not closed after Stop()")
+	}
+}
+
+func TestMockServer_Register(t *testing.T) {
+	spec, err := Parse(writeProto(t, testProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		examples map[string][]byte
+		wantErr  bool
+	}{
+		{name: "valid example", examples: map[string][]byte{"shop.Orders/GetOrder": []byte(`{"id": "123"}`)}},
+		{name: "unknown method", examples: map[string][]byte{"shop.Orders/CancelOrder": []byte(`{}`)}, wantErr: true},
+		{name: "unknown field", examples: map[string][]byte{"shop.Orders/GetOrder": []byte(`{"price": 10}`)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewMockServer().Register(spec, tt.examples); (err != nil) != tt.wantErr {
+				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// freePort returns a port that is free to listen on.
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,215 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcspec
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"io"
+	"io/ioutil"
+	"opendeps.org/opendeps/fileutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// descriptorSetExtensions are the file extensions of binary
+// FileDescriptorSets, such as those written by 'protoc --descriptor_set_out'.
+var descriptorSetExtensions = []string{".protoset", ".pb", ".desc", ".binpb"}
+
+// PartialModel holds the services defined by a .proto file, and the files
+// it imports, or by a descriptor set.
+type PartialModel struct {
+	Package  string
+	Services []*desc.ServiceDescriptor
+}
+
+// IsGrpcSpec determines, from its file extension, whether the spec
+// is a .proto file or a descriptor set.
+func IsGrpcSpec(specPath string) bool {
+	ext := strings.ToLower(path.Ext(strings.SplitN(specPath, "?", 2)[0]))
+	if ext == ".proto" {
+		return true
+	}
+	for _, setExt := range descriptorSetExtensions {
+		if ext == setExt {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads the .proto file or descriptor set at the given path or URL.
+// Imports of a .proto file are resolved relative to it.
+func Parse(specPath string) (*PartialModel, error) {
+	raw, err := fileutil.ReadAllContent(specPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseContent(specPath, raw)
+}
+
+// ParseContent reads the .proto file or descriptor set at the given path
+// or URL, whose content is raw. Imports of a .proto file are resolved
+// relative to it.
+func ParseContent(specPath string, raw []byte) (*PartialModel, error) {
+	var files []*desc.FileDescriptor
+	if strings.EqualFold(path.Ext(strings.SplitN(specPath, "?", 2)[0]), ".proto") {
+		file, err := parseProto(specPath, raw)
+		if err != nil {
+			return nil, err
+		}
+		files = []*desc.FileDescriptor{file}
+	} else {
+		set, err := parseDescriptorSet(specPath, raw)
+		if err != nil {
+			return nil, err
+		}
+		files = set
+	}
+
+	o := &PartialModel{}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if o.Package == "" && len(file.GetServices()) > 0 {
+			o.Package = file.GetPackage()
+		}
+		for _, service := range file.GetServices() {
+			if !seen[service.GetFullyQualifiedName()] {
+				seen[service.GetFullyQualifiedName()] = true
+				o.Services = append(o.Services, service)
+			}
+		}
+	}
+	sort.Slice(o.Services, func(i, j int) bool {
+		return o.Services[i].GetFullyQualifiedName() < o.Services[j].GetFullyQualifiedName()
+	})
+	logrus.Tracef("grpc spec parsed: %d service(s) from %v", len(o.Services), specPath)
+	return o, nil
+}
+
+func parseProto(specPath string, raw []byte) (*desc.FileDescriptor, error) {
+	fileName := path.Base(strings.SplitN(specPath, "?", 2)[0])
+	parser := protoparse.Parser{
+		Accessor: func(name string) (io.ReadCloser, error) {
+			if name == fileName {
+				return ioutil.NopCloser(bytes.NewReader(raw)), nil
+			}
+			location, err := fileutil.ResolveLocation(specPath, name)
+			if err != nil {
+				return nil, err
+			}
+			content, err := fileutil.ReadAllContent(location)
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		},
+		IncludeSourceCodeInfo: true,
+	}
+	files, err := parser.ParseFiles(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing proto file [%v]: %v", specPath, err)
+	}
+	return files[0], nil
+}
+
+func parseDescriptorSet(specPath string, raw []byte) ([]*desc.FileDescriptor, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(raw, set); err != nil {
+		return nil, fmt.Errorf("error parsing descriptor set [%v]: %v", specPath, err)
+	}
+	byName, err := desc.CreateFileDescriptorsFromSet(set)
+	if err != nil {
+		return nil, fmt.Errorf("error loading descriptor set [%v]: %v", specPath, err)
+	}
+
+	// only the services of files named in the set are included, not those of their dependencies
+	var files []*desc.FileDescriptor
+	for _, file := range set.GetFile() {
+		files = append(files, byName[file.GetName()])
+	}
+	return files, nil
+}
+
+// ServiceNames returns the fully qualified names of the services.
+func (o *PartialModel) ServiceNames() []string {
+	var names []string
+	for _, service := range o.Services {
+		names = append(names, service.GetFullyQualifiedName())
+	}
+	return names
+}
+
+// FindService returns the service with the fully qualified name, or nil.
+func (o *PartialModel) FindService(name string) *desc.ServiceDescriptor {
+	for _, service := range o.Services {
+		if service.GetFullyQualifiedName() == name {
+			return service
+		}
+	}
+	return nil
+}
+
+// FindMethod returns the method with the given name, in the form
+// 'package.Service/Method', or nil.
+func (o *PartialModel) FindMethod(fullMethod string) *desc.MethodDescriptor {
+	serviceName, methodName := SplitMethod(fullMethod)
+	if service := o.FindService(serviceName); service != nil && methodName != "" {
+		return service.FindMethodByName(methodName)
+	}
+	return nil
+}
+
+// Has determines whether the spec defines the service or method, given in
+// the form 'package.Service' or 'package.Service/Method'.
+func (o *PartialModel) Has(name string) bool {
+	if serviceName, methodName := SplitMethod(name); methodName == "" {
+		return o.FindService(serviceName) != nil
+	}
+	return o.FindMethod(name) != nil
+}
+
+// SplitMethod splits a name of the form 'package.Service/Method', optionally
+// with a leading '/', into its service and method names.
+func SplitMethod(name string) (service string, method string) {
+	parts := strings.SplitN(strings.TrimPrefix(name, "/"), "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+var importPattern = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+
+// FindImports returns the files imported by the raw content of a .proto file,
+// other than the well-known types, which are built in.
+func FindImports(raw []byte) []string {
+	var imports []string
+	for _, match := range importPattern.FindAllSubmatch(raw, -1) {
+		if name := string(match[1]); !strings.HasPrefix(name, "google/protobuf/") {
+			imports = append(imports, name)
+		}
+	}
+	return imports
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcspec
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testProto defines a service with a unary and a server streaming method.
+const testProto = `syntax = "proto3";
+
+package shop;
+
+service Orders {
+  rpc GetOrder (GetOrderRequest) returns (Order);
+  rpc WatchOrders (GetOrderRequest) returns (stream Order);
+}
+
+message GetOrderRequest {
+  string id = 1;
+}
+
+message Order {
+  string id = 1;
+  int32 quantity = 2;
+}
+`
+
+// writeProto writes the .proto file to a temporary directory,
+// returning its path.
+func writeProto(t *testing.T, content string) string {
+	specPath := filepath.Join(t.TempDir(), "shop.proto")
+	if err := ioutil.WriteFile(specPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return specPath
+}
+
+func TestPartialModel_Has(t *testing.T) {
+	spec, err := Parse(writeProto(t, testProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "shop.Orders", want: true},
+		{name: "shop.Orders/GetOrder", want: true},
+		{name: "/shop.Orders/WatchOrders", want: true},
+		{name: "Orders", want: false},
+		{name: "shop.Payments", want: false},
+		{name: "shop.Orders/CancelOrder", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spec.Has(tt.name); got != tt.want {
+				t.Errorf("Has() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartialModel_FindMethod(t *testing.T) {
+	spec, err := Parse(writeProto(t, testProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		fullMethod string
+		want       string
+	}{
+		{fullMethod: "shop.Orders/GetOrder", want: "shop.Orders.GetOrder"},
+		{fullMethod: "/shop.Orders/WatchOrders", want: "shop.Orders.WatchOrders"},
+		{fullMethod: "shop.Orders", want: ""},
+		{fullMethod: "shop.Orders/CancelOrder", want: ""},
+		{fullMethod: "shop.Payments/GetOrder", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fullMethod, func(t *testing.T) {
+			var got string
+			if method := spec.FindMethod(tt.fullMethod); method != nil {
+				got = method.GetFullyQualifiedName()
+			}
+			if got != tt.want {
+				t.Errorf("FindMethod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMethod(t *testing.T) {
+	tests := []struct {
+		name        string
+		wantService string
+		wantMethod  string
+	}{
+		{name: "shop.Orders", wantService: "shop.Orders"},
+		{name: "shop.Orders/GetOrder", wantService: "shop.Orders", wantMethod: "GetOrder"},
+		{name: "/shop.Orders/GetOrder", wantService: "shop.Orders", wantMethod: "GetOrder"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, method := SplitMethod(tt.name)
+			if service != tt.wantService || method != tt.wantMethod {
+				t.Errorf("SplitMethod() = %v, %v, want %v, %v", service, method, tt.wantService, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestFindImports(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{
+			name: "no imports",
+			raw:  testProto,
+		},
+		{
+			name: "imports",
+			raw:  "syntax = \"proto3\";\nimport \"common/money.proto\";\n  import public \"shared.proto\";\nimport weak \"legacy.proto\";\n",
+			want: []string{"common/money.proto", "shared.proto", "legacy.proto"},
+		},
+		{
+			name: "well-known types are built in",
+			raw:  "syntax = \"proto3\";\nimport \"google/protobuf/timestamp.proto\";\nimport \"money.proto\";\n",
+			want: []string{"money.proto"},
+		},
+		{
+			name: "commented out import",
+			raw:  "syntax = \"proto3\";\n// import \"money.proto\";\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindImports([]byte(tt.raw)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindImports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,18 +3,24 @@ package bundler
 import (
 	"gatehill.io/imposter/impostermodel"
 	"github.com/sirupsen/logrus"
-	"opendeps.org/opendeps/fileutil"
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/model"
 	"opendeps.org/opendeps/openapi"
 	"os"
 	"path/filepath"
 )
 
+// BundleManifest stages the manifest, after the manifests it extends and
+// any overlays have been merged into it, to be served by the mock.
 func BundleManifest(stagingDir string, manifestPath string, forceOverwrite bool) {
 	logrus.Debugf("bundling manifest: %v", manifestPath)
-	err := fileutil.CopyContent(manifestPath, filepath.Join(stagingDir, "opendeps.yaml"))
+	resolved, err := model.ResolveRaw(manifestPath)
 	if err != nil {
 		logrus.Fatal(err)
 	}
+	if err := ioutil.WriteFile(filepath.Join(stagingDir, "opendeps.yaml"), resolved, 0644); err != nil {
+		logrus.Fatal(err)
+	}
 
 	specFileName := "opendeps-openapi-gen.yaml"
 	writeManifestSpec(stagingDir, specFileName)
@@ -28,7 +34,7 @@ func BundleManifest(stagingDir string, manifestPath string, forceOverwrite bool)
 			},
 		},
 	}
-	openapi.WriteMockConfig(filepath.Join(stagingDir, specFileName), resources, forceOverwrite)
+	openapi.WriteMockConfig(filepath.Join(stagingDir, specFileName), "", resources, forceOverwrite)
 }
 
 // writeManifestSpec creates an OpenAPI spec describing the well known endpoint
@@ -0,0 +1,340 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/model"
+	"strconv"
+	"strings"
+)
+
+// Editor modifies a manifest at the level of YAML nodes,
+// so that comments and key order are preserved.
+type Editor struct {
+	doc *yaml.Node
+}
+
+// New creates an editor for a new manifest.
+func New(manifest *model.OpenDeps) (*Editor, error) {
+	root := &yaml.Node{}
+	if err := root.Encode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %v", err)
+	}
+	return &Editor{
+		doc: &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}},
+	}, nil
+}
+
+// Load creates an editor for the manifest at the given path.
+func Load(manifestPath string) (*Editor, error) {
+	raw, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest [%v]: %v", manifestPath, err)
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(raw, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest [%v]: %v", manifestPath, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("manifest [%v] is not a YAML mapping", manifestPath)
+	}
+	return &Editor{doc: doc}, nil
+}
+
+func (e *Editor) root() *yaml.Node {
+	return e.doc.Content[0]
+}
+
+// dependencies returns the mapping node holding the dependencies,
+// creating it if create is true and it does not exist.
+func (e *Editor) dependencies(create bool) *yaml.Node {
+	deps := findValue(e.root(), "dependencies")
+	if deps == nil || deps.Kind != yaml.MappingNode {
+		if !create {
+			return nil
+		}
+		deps = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setValue(e.root(), "dependencies", deps)
+	}
+	return deps
+}
+
+// DependencyNames returns the names of the dependencies, in document order.
+func (e *Editor) DependencyNames() []string {
+	var names []string
+	if deps := e.dependencies(false); deps != nil {
+		for i := 0; i < len(deps.Content)-1; i += 2 {
+			names = append(names, deps.Content[i].Value)
+		}
+	}
+	return names
+}
+
+// HasDependency determines whether the manifest declares the dependency.
+func (e *Editor) HasDependency(name string) bool {
+	deps := e.dependencies(false)
+	return deps != nil && findValue(deps, name) != nil
+}
+
+// DependencySpec returns the spec location of the dependency, or an
+// empty string if the dependency or its spec is not declared.
+func (e *Editor) DependencySpec(name string) string {
+	deps := e.dependencies(false)
+	if deps == nil {
+		return ""
+	}
+	if dep := findValue(deps, name); dep != nil && dep.Kind == yaml.MappingNode {
+		if spec := findValue(dep, "spec"); spec != nil && spec.Kind == yaml.ScalarNode {
+			return spec.Value
+		}
+	}
+	return ""
+}
+
+// SetDependency adds the dependency, or, if it exists, updates it with the
+// non-empty fields of dep. Existing fields keep their position and comments.
+func (e *Editor) SetDependency(name string, dep model.Dependency) error {
+	updated := &yaml.Node{}
+	if err := updated.Encode(dep); err != nil {
+		return fmt.Errorf("failed to encode dependency [%v]: %v", name, err)
+	}
+
+	deps := e.dependencies(true)
+	if existing := findValue(deps, name); existing != nil && existing.Kind == yaml.MappingNode {
+		mergeMapping(existing, updated)
+	} else {
+		setValue(deps, name, updated)
+	}
+	return nil
+}
+
+// AddSecurityConfig adds the security config to the manifest's components,
+// returning false, and leaving the manifest unchanged, if a config with the
+// same name exists.
+func (e *Editor) AddSecurityConfig(name string, config model.SecurityConfig) (bool, error) {
+	encoded := &yaml.Node{}
+	components := model.Components{SecurityConfigs: map[string]model.SecurityConfig{name: config}}
+	if err := encoded.Encode(components); err != nil {
+		return false, fmt.Errorf("failed to encode security config [%v]: %v", name, err)
+	}
+	configsKey, configs := encoded.Content[0].Value, encoded.Content[1]
+
+	existingComponents := findValue(e.root(), "components")
+	if existingComponents == nil || existingComponents.Kind != yaml.MappingNode {
+		existingComponents = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setValue(e.root(), "components", existingComponents)
+	}
+	existingConfigs := findValue(existingComponents, configsKey)
+	if existingConfigs == nil || existingConfigs.Kind != yaml.MappingNode {
+		setValue(existingComponents, configsKey, configs)
+		return true, nil
+	}
+	if findValue(existingConfigs, name) != nil {
+		return false, nil
+	}
+	setValue(existingConfigs, name, configs.Content[1])
+	return true, nil
+}
+
+// RemoveDependency removes the dependency, returning
+// whether it was present.
+func (e *Editor) RemoveDependency(name string) bool {
+	deps := e.dependencies(false)
+	if deps == nil {
+		return false
+	}
+	for i := 0; i < len(deps.Content)-1; i += 2 {
+		if deps.Content[i].Value == name {
+			deps.Content = append(deps.Content[:i], deps.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// DependencyComment returns the comment on the line of the dependency's
+// name, without the leading '#'.
+func (e *Editor) DependencyComment(name string) string {
+	if key := e.dependencyKey(name); key != nil {
+		return strings.TrimSpace(strings.TrimPrefix(key.LineComment, "#"))
+	}
+	return ""
+}
+
+// SetDependencyComment sets the comment on the line of the dependency's name.
+// An empty comment removes it.
+func (e *Editor) SetDependencyComment(name string, comment string) {
+	if key := e.dependencyKey(name); key != nil {
+		if comment == "" {
+			key.LineComment = ""
+		} else {
+			key.LineComment = "# " + comment
+		}
+	}
+}
+
+// SetAvailabilityPathComment sets the comment on the line of the availability
+// path of the dependency, if it has one. An empty comment removes it.
+func (e *Editor) SetAvailabilityPathComment(name string, comment string) {
+	deps := e.dependencies(false)
+	if deps == nil {
+		return
+	}
+	dep := findValue(deps, name)
+	if dep == nil || dep.Kind != yaml.MappingNode {
+		return
+	}
+	availability := findValue(dep, "availability")
+	if availability == nil || availability.Kind != yaml.MappingNode {
+		return
+	}
+	if path := findValue(availability, "path"); path != nil {
+		if comment == "" {
+			path.LineComment = ""
+		} else {
+			path.LineComment = "# " + comment
+		}
+	}
+}
+
+// dependencyKey returns the key node of the dependency, or nil.
+func (e *Editor) dependencyKey(name string) *yaml.Node {
+	deps := e.dependencies(false)
+	if deps == nil {
+		return nil
+	}
+	for i := 0; i < len(deps.Content)-1; i += 2 {
+		if deps.Content[i].Value == name {
+			return deps.Content[i]
+		}
+	}
+	return nil
+}
+
+// Locate returns the line and column of the value at the JSON pointer, such
+// as '/dependencies/foo/spec', and whether it exists in the manifest. Values
+// in mappings are located at their key. If the value does not exist in the
+// manifest, such as when it is inherited from a manifest it extends, the line
+// and column of its closest ancestor are returned.
+func (e *Editor) Locate(pointer string) (int, int, bool) {
+	node := e.root()
+	line, column := node.Line, node.Column
+	if pointer == "" {
+		return line, column, true
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i < len(node.Content)-1; i += 2 {
+				if node.Content[i].Value == token {
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(token); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+				line, column = next.Line, next.Column
+			}
+		}
+		if next == nil {
+			return line, column, false
+		}
+		node = next
+	}
+	return line, column, true
+}
+
+// Bytes serialises the manifest.
+func (e *Editor) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(e.doc); err != nil {
+		return nil, fmt.Errorf("failed to serialise manifest: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to serialise manifest: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the manifest to the given path.
+func (e *Editor) Save(manifestPath string) error {
+	content, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(manifestPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest [%v]: %v", manifestPath, err)
+	}
+	return nil
+}
+
+// findValue returns the value for key in a mapping node, or nil.
+func findValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setValue replaces the value for key in a mapping node, retaining the
+// key's position, or appends the key if it does not exist.
+func setValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+// mergeMapping updates target with the entries of source, recursing into
+// nested mappings. Scalars that are replaced keep their comments.
+func mergeMapping(target *yaml.Node, source *yaml.Node) {
+	for i := 0; i < len(source.Content)-1; i += 2 {
+		key, value := source.Content[i].Value, source.Content[i+1]
+		existing := findValue(target, key)
+		switch {
+		case existing == nil:
+			setValue(target, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeMapping(existing, value)
+		case existing.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode:
+			existing.Value = value.Value
+			existing.Tag = value.Tag
+			existing.Style = value.Style
+		default:
+			value.HeadComment, value.LineComment = existing.HeadComment, existing.LineComment
+			setValue(target, key, value)
+		}
+	}
+}
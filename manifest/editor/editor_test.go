@@ -0,0 +1,71 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package editor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const locateManifest = `openDeps: 0.1.0
+info:
+  title: Example
+dependencies:
+  foo_service:
+    spec: foo.yaml
+    x-channels:
+      - orders
+      - payments
+  "a/b~c":
+    spec: abc.yaml
+`
+
+func TestLocate(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "opendeps.yaml")
+	if err := ioutil.WriteFile(manifestPath, []byte(locateManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifestEditor, err := Load(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		pointer    string
+		wantLine   int
+		wantColumn int
+		wantFound  bool
+	}{
+		{name: "root", pointer: "", wantLine: 1, wantColumn: 1, wantFound: true},
+		{name: "top-level key", pointer: "/info", wantLine: 2, wantColumn: 1, wantFound: true},
+		{name: "nested key", pointer: "/dependencies/foo_service/spec", wantLine: 6, wantColumn: 5, wantFound: true},
+		{name: "sequence item", pointer: "/dependencies/foo_service/x-channels/1", wantLine: 9, wantColumn: 9, wantFound: true},
+		{name: "escaped key", pointer: "/dependencies/a~1b~0c/spec", wantLine: 11, wantColumn: 5, wantFound: true},
+		{name: "missing key uses closest ancestor", pointer: "/dependencies/foo_service/availability/path", wantLine: 5, wantColumn: 3, wantFound: false},
+		{name: "sequence index out of range", pointer: "/dependencies/foo_service/x-channels/5", wantLine: 7, wantColumn: 5, wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, column, found := manifestEditor.Locate(tt.pointer)
+			if line != tt.wantLine || column != tt.wantColumn || found != tt.wantFound {
+				t.Errorf("Locate(%q) = %d:%d %v, want %d:%d %v", tt.pointer, line, column, found, tt.wantLine, tt.wantColumn, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/manifest/severity"
+	"sort"
+	"strings"
+)
+
+// SeverityOff disables a rule when set in the config.
+const SeverityOff = "off"
+
+// Rule is a check of a manifest.
+type Rule struct {
+	Id          string
+	Description string
+	Severity    severity.Severity
+
+	check func(m *manifest) []Finding
+}
+
+// Finding is a problem with a manifest, found by a rule.
+type Finding struct {
+	RuleId   string
+	Severity severity.Severity
+	Message  string
+	Line     int
+	Column   int
+
+	// Suppressed is true if the finding is suppressed by a comment in the manifest
+	Suppressed bool
+}
+
+// Config enables, disables and sets the severity of rules.
+type Config struct {
+	// Rules maps rule IDs to 'off' or false, to disable the rule, true, to
+	// enable it, or to the severity that replaces its default
+	Rules map[string]string
+}
+
+// Result holds the findings of the rules that were run against a manifest.
+type Result struct {
+	ManifestPath string
+
+	// Rules that were run, with their configured severity
+	Rules    []Rule
+	Findings []Finding
+}
+
+// manifest is the manifest being linted, as both YAML nodes,
+// which record the position of each value, and its model.
+type manifest struct {
+	path  string
+	root  *yaml.Node
+	model *model.OpenDeps
+}
+
+// Lint runs the enabled rules against the manifest file. The manifest
+// is linted as written, without resolving the manifests it extends,
+// its overlays or environment variables, so that findings can be
+// reported at their position in the file.
+func Lint(manifestPath string, config Config) (*Result, error) {
+	rules, err := configureRules(config)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest [%v]: %v", manifestPath, err)
+	}
+	m, err := parseManifest(manifestPath, raw)
+	if err != nil {
+		return nil, err
+	}
+	suppressions := parseSuppressions(raw)
+
+	result := &Result{ManifestPath: manifestPath, Rules: rules}
+	for _, rule := range rules {
+		for _, finding := range rule.check(m) {
+			finding.RuleId = rule.Id
+			finding.Severity = rule.Severity
+			finding.Suppressed = suppressions.suppresses(rule.Id, finding.Line)
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+	sort.SliceStable(result.Findings, func(i, j int) bool {
+		a, b := result.Findings[i], result.Findings[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return result, nil
+}
+
+// Unsuppressed returns the findings that are not suppressed.
+func (r *Result) Unsuppressed() []Finding {
+	var findings []Finding
+	for _, finding := range r.Findings {
+		if !finding.Suppressed {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// configureRules returns the built-in rules that are enabled by the
+// config, with their configured severity.
+func configureRules(config Config) ([]Rule, error) {
+	builtIn := make(map[string]bool)
+	for _, rule := range Rules() {
+		builtIn[rule.Id] = true
+	}
+	for id := range config.Rules {
+		if !builtIn[id] {
+			return nil, fmt.Errorf("unknown lint rule [%v] in config", id)
+		}
+	}
+
+	var rules []Rule
+	for _, rule := range Rules() {
+		if setting, found := config.Rules[rule.Id]; found {
+			// YAML 1.1 reads an unquoted 'off' as false
+			if strings.EqualFold(setting, SeverityOff) || setting == "false" {
+				continue
+			} else if setting == "true" {
+				rules = append(rules, rule)
+				continue
+			}
+			configured, err := severity.Parse(setting)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config for lint rule [%v]: %v", rule.Id, err)
+			}
+			rule.Severity = configured
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseManifest(manifestPath string, raw []byte) (*manifest, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(raw, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest [%v]: %v", manifestPath, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("manifest [%v] is not a YAML mapping", manifestPath)
+	}
+	parsed := &model.OpenDeps{}
+	if err := yaml.NewDecoder(bytes.NewReader(raw)).Decode(parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest [%v]: %v", manifestPath, err)
+	}
+	return &manifest{path: manifestPath, root: doc.Content[0], model: parsed}, nil
+}
+
+// lookup returns the key and value nodes of the entry at the path of keys,
+// or nils if it does not exist.
+func (m *manifest) lookup(keys ...string) (*yaml.Node, *yaml.Node) {
+	var keyNode *yaml.Node
+	node := m.root
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		mapping := node
+		keyNode, node = nil, nil
+		for i := 0; i < len(mapping.Content)-1; i += 2 {
+			if mapping.Content[i].Value == key {
+				keyNode, node = mapping.Content[i], mapping.Content[i+1]
+				break
+			}
+		}
+	}
+	return keyNode, node
+}
+
+// dependencyNames returns the names of the dependencies, in document order.
+func (m *manifest) dependencyNames() []string {
+	var names []string
+	if _, deps := m.lookup("dependencies"); deps != nil && deps.Kind == yaml.MappingNode {
+		for i := 0; i < len(deps.Content)-1; i += 2 {
+			names = append(names, deps.Content[i].Value)
+		}
+	}
+	return names
+}
+
+// findingAt returns a finding positioned at the node, or at the
+// start of the manifest if the node is nil.
+func findingAt(node *yaml.Node, format string, args ...interface{}) Finding {
+	finding := Finding{Message: fmt.Sprintf(format, args...), Line: 1, Column: 1}
+	if node != nil {
+		finding.Line, finding.Column = node.Line, node.Column
+	}
+	return finding
+}
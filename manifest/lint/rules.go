@@ -0,0 +1,179 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"gopkg.in/yaml.v3"
+	"net"
+	"net/url"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/severity"
+	"opendeps.org/opendeps/openapi"
+	"opendeps.org/opendeps/spechandlers"
+	"strings"
+)
+
+// Rules returns the built-in rules, with their default severity.
+func Rules() []Rule {
+	return []Rule{
+		{
+			Id:          "required-availability",
+			Description: "Required dependencies with an OpenAPI or Swagger spec must have an availability path or URL",
+			Severity:    severity.Error,
+			check:       checkRequiredAvailability,
+		},
+		{
+			Id:          "contact-email",
+			Description: "The manifest must have a contact email",
+			Severity:    severity.Warning,
+			check:       checkContactEmail,
+		},
+		{
+			Id:          "pinned-version",
+			Description: "Dependencies must be pinned to a version or range, not 'latest' or '*'",
+			Severity:    severity.Warning,
+			check:       checkPinnedVersion,
+		},
+		{
+			Id:          "no-insecure-urls",
+			Description: "URLs must use https://, other than those of the local host",
+			Severity:    severity.Error,
+			check:       checkInsecureUrls,
+		},
+		{
+			Id:          "dependency-summary",
+			Description: "Dependencies should have a summary",
+			Severity:    severity.Note,
+			check:       checkDependencySummary,
+		},
+	}
+}
+
+// checkRequiredAvailability skips dependencies with other kinds of spec,
+// as their availability is checked without a path.
+func checkRequiredAvailability(m *manifest) []Finding {
+	var findings []Finding
+	for _, depName := range m.dependencyNames() {
+		dep := m.model.Dependencies[depName]
+		if !dep.Required || (dep.Availability != nil && (dep.Availability.Path != "" || dep.Availability.Url != "")) {
+			continue
+		}
+		if dep.Spec != "" && detectSpecHandler(fileutil.MakeAbsoluteRelativeToFile(dep.Spec, m.path)) != openapi.Handler {
+			continue
+		}
+		key, _ := m.lookup("dependencies", depName)
+		findings = append(findings, findingAt(key, "required dependency [%v] has no availability path", depName))
+	}
+	return findings
+}
+
+// detectSpecHandler returns the handler for the spec, reading only local
+// specs, so that linting does not need network access. Remote specs are
+// detected by their location, such as a '.proto' extension, so remote
+// AsyncAPI specs are treated as OpenAPI specs.
+func detectSpecHandler(specPath string) openapi.SpecHandler {
+	var raw []byte
+	if !fileutil.IsRemote(specPath) {
+		raw, _ = fileutil.ReadAllContent(specPath)
+	}
+	return spechandlers.DetectForContent(specPath, raw)
+}
+
+func checkContactEmail(m *manifest) []Finding {
+	if m.model.Info != nil && m.model.Info.Contact != nil && m.model.Info.Contact.Email != "" {
+		return nil
+	}
+	for _, keys := range [][]string{{"info", "contact"}, {"info"}} {
+		if key, _ := m.lookup(keys...); key != nil {
+			return []Finding{findingAt(key, "manifest has no contact email")}
+		}
+	}
+	return []Finding{findingAt(nil, "manifest has no contact email")}
+}
+
+// unpinnedVersions are versions that are satisfied by any version.
+var unpinnedVersions = map[string]bool{
+	"latest": true,
+	"*":      true,
+	"x":      true,
+}
+
+func checkPinnedVersion(m *manifest) []Finding {
+	var findings []Finding
+	for _, depName := range m.dependencyNames() {
+		version := m.model.Dependencies[depName].Version
+		if version == "" {
+			key, _ := m.lookup("dependencies", depName)
+			findings = append(findings, findingAt(key, "dependency [%v] has no version", depName))
+		} else if unpinnedVersions[strings.ToLower(version)] {
+			_, value := m.lookup("dependencies", depName, "version")
+			findings = append(findings, findingAt(value, "dependency [%v] version is '%v' - pin it to a version or range", depName, version))
+		}
+	}
+	return findings
+}
+
+// checkInsecureUrls checks every value in the manifest, so
+// that URLs in extensions and environments are included.
+func checkInsecureUrls(m *manifest) []Finding {
+	var findings []Finding
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode {
+			if isInsecureUrl(node.Value) {
+				findings = append(findings, findingAt(node, "insecure URL [%v] - use https:// instead", node.Value))
+			}
+			return
+		}
+		for i, child := range node.Content {
+			// skip mapping keys
+			if node.Kind == yaml.MappingNode && i%2 == 0 {
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(m.root)
+	return findings
+}
+
+func isInsecureUrl(value string) bool {
+	if !strings.HasPrefix(strings.ToLower(value), "http://") {
+		return false
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return true
+	}
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsLoopback()
+}
+
+func checkDependencySummary(m *manifest) []Finding {
+	var findings []Finding
+	for _, depName := range m.dependencyNames() {
+		if m.model.Dependencies[depName].Summary == "" {
+			key, _ := m.lookup("dependencies", depName)
+			findings = append(findings, findingAt(key, "dependency [%v] has no summary", depName))
+		}
+	}
+	return findings
+}
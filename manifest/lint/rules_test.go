@@ -0,0 +1,292 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/severity"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// position is where a finding is reported, with its message.
+type position struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// runCheck runs the check against the manifest, written to a temporary
+// directory with the spec files, returning the positions of the findings.
+func runCheck(t *testing.T, check func(m *manifest) []Finding, manifestYaml string, specs map[string]string) []position {
+	dir := t.TempDir()
+	for name, content := range specs {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifestPath := filepath.Join(dir, "opendeps.yaml")
+	m, err := parseManifest(manifestPath, []byte(manifestYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var positions []position
+	for _, finding := range check(m) {
+		positions = append(positions, position{finding.Line, finding.Column, finding.Message})
+	}
+	return positions
+}
+
+func TestCheckRequiredAvailability(t *testing.T) {
+	specs := map[string]string{
+		"openapi.yaml":  "openapi: 3.0.0\ninfo: {title: foo, version: 1.0.0}\npaths: {}\n",
+		"asyncapi.yaml": "asyncapi: 2.0.0\ninfo: {title: events, version: 1.0.0}\nchannels: {}\n",
+		"service.proto": "syntax = \"proto3\";\n",
+	}
+	tests := []struct {
+		name     string
+		manifest string
+		want     []position
+	}{
+		{
+			name: "required OpenAPI dependency without availability",
+			manifest: `dependencies:
+  foo:
+    spec: ./openapi.yaml
+    required: true
+`,
+			want: []position{{2, 3, "required dependency [foo] has no availability path"}},
+		},
+		{
+			name: "required OpenAPI dependency with availability path",
+			manifest: `dependencies:
+  foo:
+    spec: ./openapi.yaml
+    required: true
+    availability:
+      path: /health
+`,
+		},
+		{
+			name: "optional OpenAPI dependency without availability",
+			manifest: `dependencies:
+  foo:
+    spec: ./openapi.yaml
+`,
+		},
+		{
+			name: "required dependencies with other kinds of spec",
+			manifest: `dependencies:
+  events:
+    spec: ./asyncapi.yaml
+    required: true
+  service:
+    spec: ./service.proto
+    required: true
+`,
+		},
+		{
+			name: "remote specs are detected by location",
+			manifest: `dependencies:
+  remote_grpc:
+    spec: https://specs.example.invalid/service.proto
+    required: true
+  remote_openapi:
+    spec: https://specs.example.invalid/openapi.yaml
+    required: true
+`,
+			want: []position{{5, 3, "required dependency [remote_openapi] has no availability path"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runCheck(t, checkRequiredAvailability, tt.manifest, specs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkRequiredAvailability() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckInsecureUrls(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []position
+	}{
+		{
+			name: "http URLs anywhere in the manifest",
+			manifest: `dependencies:
+  foo:
+    spec: http://specs.example.com/foo.yaml
+    availability:
+      url: https://foo.example.com/health
+x-environments:
+  dev:
+    servers:
+      foo: http://foo.dev.example.com
+`,
+			want: []position{
+				{3, 11, "insecure URL [http://specs.example.com/foo.yaml] - use https:// instead"},
+				{9, 12, "insecure URL [http://foo.dev.example.com] - use https:// instead"},
+			},
+		},
+		{
+			name: "local host and loopback addresses",
+			manifest: `dependencies:
+  foo:
+    spec: http://localhost:8080/foo.yaml
+  bar:
+    spec: http://127.0.0.1/bar.yaml
+  baz:
+    spec: http://[::1]:8080/baz.yaml
+`,
+		},
+		{
+			name: "other addresses",
+			manifest: `dependencies:
+  foo:
+    spec: http://10.0.0.1/foo.yaml
+  bar:
+    spec: http://localhost.example.com/bar.yaml
+`,
+			want: []position{
+				{3, 11, "insecure URL [http://10.0.0.1/foo.yaml] - use https:// instead"},
+				{5, 11, "insecure URL [http://localhost.example.com/bar.yaml] - use https:// instead"},
+			},
+		},
+		{
+			name: "mapping keys are ignored",
+			manifest: `x-links:
+  http://example.com: docs
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runCheck(t, checkInsecureUrls, tt.manifest, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkInsecureUrls() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPinnedVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []position
+	}{
+		{
+			name: "pinned versions and ranges",
+			manifest: `dependencies:
+  foo:
+    version: 1.2.3
+  bar:
+    version: ^2.0.0
+`,
+		},
+		{
+			name: "missing version",
+			manifest: `dependencies:
+  foo:
+    spec: foo.yaml
+`,
+			want: []position{{2, 3, "dependency [foo] has no version"}},
+		},
+		{
+			name: "unpinned versions",
+			manifest: `dependencies:
+  foo:
+    version: latest
+  bar:
+    version: "*"
+  baz:
+    version: X
+`,
+			want: []position{
+				{3, 14, "dependency [foo] version is 'latest' - pin it to a version or range"},
+				{5, 14, "dependency [bar] version is '*' - pin it to a version or range"},
+				{7, 14, "dependency [baz] version is 'X' - pin it to a version or range"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runCheck(t, checkPinnedVersion, tt.manifest, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkPinnedVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   map[string]string
+		want    map[string]severity.Severity
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]severity.Severity{
+				"required-availability": severity.Error,
+				"contact-email":         severity.Warning,
+				"pinned-version":        severity.Warning,
+				"no-insecure-urls":      severity.Error,
+				"dependency-summary":    severity.Note,
+			},
+		},
+		{
+			name:  "off, false, true and severities",
+			rules: map[string]string{"contact-email": "off", "dependency-summary": "false", "pinned-version": "true", "no-insecure-urls": "Warning"},
+			want: map[string]severity.Severity{
+				"required-availability": severity.Error,
+				"pinned-version":        severity.Warning,
+				"no-insecure-urls":      severity.Warning,
+			},
+		},
+		{
+			name:    "unknown rule",
+			rules:   map[string]string{"no-such-rule": "error"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid severity",
+			rules:   map[string]string{"contact-email": "fatal"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := configureRules(Config{Rules: tt.rules})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configureRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make(map[string]severity.Severity)
+			for _, rule := range rules {
+				got[rule.Id] = rule.Severity
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("configureRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
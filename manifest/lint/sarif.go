@@ -0,0 +1,42 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import "opendeps.org/opendeps/sarif"
+
+// Sarif returns the result as a SARIF log, so findings can be shown by code
+// review tools. The manifest's location is given relative to baseDir, which
+// should be the root of the repository. Suppressed findings are included,
+// marked as suppressed in source.
+func (r *Result) Sarif(baseDir string) ([]byte, error) {
+	var rules []sarif.Rule
+	for _, rule := range r.Rules {
+		rules = append(rules, sarif.Rule{Id: rule.Id, Description: rule.Description, Level: string(rule.Severity)})
+	}
+	var results []sarif.Result
+	for _, finding := range r.Findings {
+		results = append(results, sarif.Result{
+			RuleId:     finding.RuleId,
+			Level:      string(finding.Severity),
+			Message:    finding.Message,
+			Line:       finding.Line,
+			Column:     finding.Column,
+			Suppressed: finding.Suppressed,
+		})
+	}
+	return sarif.Marshal(r.ManifestPath, baseDir, rules, results)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"regexp"
+	"strings"
+)
+
+// suppressionPattern matches comments such as
+// '# opendeps-lint-disable-line pinned-version, contact-email'.
+var suppressionPattern = regexp.MustCompile(`#\s*opendeps-lint-(disable-next-line|disable-line|disable)(?:\s+([\w\-,\s]+))?\s*$`)
+
+// suppression disables some rules, or all rules if none are listed,
+// for the lines from start to end.
+type suppression struct {
+	start, end int
+	ruleIds    map[string]bool
+}
+
+type suppressions []suppression
+
+// parseSuppressions finds the suppression comments in the raw manifest:
+//   - 'opendeps-lint-disable-line' applies to the line it is on
+//   - 'opendeps-lint-disable-next-line' applies to the line after it
+//   - 'opendeps-lint-disable' applies to the line it is on and all lines after it
+func parseSuppressions(raw []byte) suppressions {
+	var found suppressions
+	lines := strings.Split(string(raw), "\n")
+	for i, line := range lines {
+		match := suppressionPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lineNumber := i + 1
+		s := suppression{start: lineNumber, end: lineNumber}
+		switch match[1] {
+		case "disable":
+			s.end = len(lines)
+		case "disable-next-line":
+			s.start, s.end = lineNumber+1, lineNumber+1
+		}
+		for _, id := range strings.FieldsFunc(match[2], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if s.ruleIds == nil {
+				s.ruleIds = make(map[string]bool)
+			}
+			s.ruleIds[id] = true
+		}
+		found = append(found, s)
+	}
+	return found
+}
+
+// suppresses determines whether findings of the rule on the line are suppressed.
+func (ss suppressions) suppresses(ruleId string, line int) bool {
+	for _, s := range ss {
+		if line >= s.start && line <= s.end && (s.ruleIds == nil || s.ruleIds[ruleId]) {
+			return true
+		}
+	}
+	return false
+}
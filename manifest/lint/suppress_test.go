@@ -0,0 +1,91 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSuppressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     suppressions
+	}{
+		{
+			name:     "no suppressions",
+			manifest: "openDeps: 0.1.0\n# a comment\n",
+		},
+		{
+			name:     "disable line for one rule",
+			manifest: "openDeps: 0.1.0\ninfo: # opendeps-lint-disable-line contact-email\n",
+			want:     suppressions{{start: 2, end: 2, ruleIds: map[string]bool{"contact-email": true}}},
+		},
+		{
+			name:     "disable next line for rules separated by commas and spaces",
+			manifest: "# opendeps-lint-disable-next-line pinned-version, contact-email\ninfo:\n",
+			want:     suppressions{{start: 2, end: 2, ruleIds: map[string]bool{"pinned-version": true, "contact-email": true}}},
+		},
+		{
+			name:     "disable all rules to end of file",
+			manifest: "openDeps: 0.1.0\n# opendeps-lint-disable\ninfo:\n",
+			want:     suppressions{{start: 2, end: 4}},
+		},
+		{
+			name:     "comment must end the line",
+			manifest: "info: # opendeps-lint-disable-line contact-email - see issue #12\n",
+		},
+		{
+			name:     "unknown directive",
+			manifest: "info: # opendeps-lint-enable contact-email\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSuppressions([]byte(tt.manifest)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSuppressions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuppresses(t *testing.T) {
+	ss := suppressions{
+		{start: 2, end: 2, ruleIds: map[string]bool{"contact-email": true}},
+		{start: 5, end: 10},
+	}
+	tests := []struct {
+		name   string
+		ruleId string
+		line   int
+		want   bool
+	}{
+		{name: "listed rule on line", ruleId: "contact-email", line: 2, want: true},
+		{name: "other rule on line", ruleId: "pinned-version", line: 2, want: false},
+		{name: "listed rule on other line", ruleId: "contact-email", line: 3, want: false},
+		{name: "any rule in range", ruleId: "pinned-version", line: 7, want: true},
+		{name: "after range", ruleId: "pinned-version", line: 11, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ss.suppresses(tt.ruleId, tt.line); got != tt.want {
+				t.Errorf("suppresses(%v, %d) = %v, want %v", tt.ruleId, tt.line, got, tt.want)
+			}
+		})
+	}
+}
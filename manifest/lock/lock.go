@@ -0,0 +1,183 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lock
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/spechandlers"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const LockFileName = "opendeps.lock"
+const LockFileVersion = 1
+
+type LockedDependency struct {
+	Spec     string `yaml:"spec"`
+	Resolved string `yaml:"resolved"`
+	Digest   string `yaml:"digest"`
+	Version  string `yaml:"version,omitempty"`
+}
+
+type LockFile struct {
+	LockVersion  int                         `yaml:"lockVersion"`
+	Dependencies map[string]LockedDependency `yaml:"dependencies"`
+}
+
+// GetLockFilePath returns the path of the lock file for the given manifest,
+// which is always adjacent to the manifest.
+func GetLockFilePath(manifestPath string) string {
+	return filepath.Join(filepath.Dir(manifestPath), LockFileName)
+}
+
+// Generate fetches the spec of each dependency in the manifest and
+// records its resolved location, digest and version.
+func Generate(manifestPath string, manifest *model.OpenDeps) (*LockFile, error) {
+	lockFile := &LockFile{
+		LockVersion:  LockFileVersion,
+		Dependencies: make(map[string]LockedDependency),
+	}
+	for depName, dep := range manifest.Dependencies {
+		locked, _, err := resolve(manifestPath, dep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock dependency [%v]: %v", depName, err)
+		}
+		lockFile.Dependencies[depName] = *locked
+	}
+	return lockFile, nil
+}
+
+// resolve returns the locked form of the dependency, and the
+// content of its spec, from which the digest was computed.
+func resolve(manifestPath string, dep model.Dependency) (*LockedDependency, []byte, error) {
+	specNormalisedPath := fileutil.MakeAbsoluteRelativeToFile(dep.Spec, manifestPath)
+	reader, err := fileutil.ReadContent(specNormalisedPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	raw, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read spec [%v]: %v", specNormalisedPath, err)
+	}
+	locked := &LockedDependency{
+		Spec:     dep.Spec,
+		Resolved: makeResolvedLocation(specNormalisedPath, manifestPath),
+		Digest:   fmt.Sprintf("sha256:%x", sha256.Sum256(raw)),
+	}
+
+	// the version is taken from the metadata of the spec, if its kind has one
+	scaffolded, err := spechandlers.DetectForContent(specNormalisedPath, raw).Scaffold(specNormalisedPath, raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse spec [%v]: %v", specNormalisedPath, err)
+	}
+	locked.Version = scaffolded.Version
+	return locked, raw, nil
+}
+
+// makeResolvedLocation keeps local spec paths relative to the manifest, so the
+// lock file is portable between machines.
+func makeResolvedLocation(specNormalisedPath string, manifestPath string) string {
+	if fileutil.IsRemote(specNormalisedPath) || !filepath.IsAbs(specNormalisedPath) {
+		return specNormalisedPath
+	}
+	relPath, err := filepath.Rel(filepath.Dir(manifestPath), specNormalisedPath)
+	if err != nil {
+		return specNormalisedPath
+	}
+	return "./" + filepath.ToSlash(relPath)
+}
+
+// Load reads the lock file at the given path. If no lock file
+// exists, nil is returned without an error.
+func Load(lockFilePath string) (*LockFile, error) {
+	raw, err := ioutil.ReadFile(lockFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read lock file [%v]: %v", lockFilePath, err)
+	}
+	lockFile := &LockFile{}
+	if err := yaml.Unmarshal(raw, lockFile); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file [%v]: %v", lockFilePath, err)
+	}
+	if lockFile.LockVersion != LockFileVersion {
+		return nil, fmt.Errorf("unsupported lock file version %d in [%v]", lockFile.LockVersion, lockFilePath)
+	}
+	return lockFile, nil
+}
+
+// Write serialises the lock file to the given path, replacing any existing file.
+func Write(lockFile *LockFile, lockFilePath string) error {
+	marshalled, err := yaml.Marshal(lockFile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file: %v", err)
+	}
+	header := "# This file is generated by 'opendeps lock'. Do not edit it by hand.\n"
+	err = ioutil.WriteFile(lockFilePath, append([]byte(header), marshalled...), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write lock file [%v]: %v", lockFilePath, err)
+	}
+	logrus.Debugf("wrote lock file: %v", lockFilePath)
+	return nil
+}
+
+// Verify checks that the specs currently resolved for the manifest match
+// those recorded in the lock file, returning a description of each mismatch.
+// The content of each spec that matches is pinned, so that later reads of
+// the spec are served the verified content, rather than fetching it again.
+func Verify(manifestPath string, manifest *model.OpenDeps, lockFile *LockFile) []string {
+	var mismatches []string
+	for depName, dep := range manifest.Dependencies {
+		locked, found := lockFile.Dependencies[depName]
+		if !found {
+			mismatches = append(mismatches, fmt.Sprintf("%v: not present in lock file", depName))
+			continue
+		}
+		if locked.Spec != dep.Spec {
+			mismatches = append(mismatches, fmt.Sprintf("%v: spec changed from %v to %v", depName, locked.Spec, dep.Spec))
+			continue
+		}
+		current, raw, err := resolve(manifestPath, dep)
+		if err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("%v: %v", depName, strings.TrimSpace(err.Error())))
+			continue
+		}
+		if current.Digest != locked.Digest {
+			mismatches = append(mismatches, fmt.Sprintf("%v: spec digest changed from %v to %v (version %v -> %v)", depName, locked.Digest, current.Digest, locked.Version, current.Version))
+			continue
+		}
+		fileutil.PinContent(fileutil.MakeAbsoluteRelativeToFile(dep.Spec, manifestPath), raw)
+	}
+	for depName := range lockFile.Dependencies {
+		if _, found := manifest.Dependencies[depName]; !found {
+			mismatches = append(mismatches, fmt.Sprintf("%v: present in lock file but not in manifest", depName))
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches
+}
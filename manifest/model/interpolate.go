@@ -0,0 +1,127 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// strictEnv causes interpolation to fail if a variable is unset and has no default.
+var strictEnv = false
+
+// envVarPattern matches `${VAR}`, `${VAR:-default}` and the `$$` escape.
+var envVarPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?}`)
+
+// ConfigureInterpolation sets whether manifests referencing unset
+// environment variables without a default fail to parse.
+func ConfigureInterpolation(strict bool) {
+	strictEnv = strict
+}
+
+// interpolator substitutes environment variables, recording those that are unset.
+type interpolator struct {
+	unset map[string]bool
+}
+
+func (i *interpolator) interpolate(value string) string {
+	if !strings.Contains(value, "$") {
+		return value
+	}
+	return envVarPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := envVarPattern.FindStringSubmatch(match)
+		name, hasDefault, defaultValue := groups[1], groups[2] != "", groups[3]
+		if envValue, found := os.LookupEnv(name); found && envValue != "" {
+			return envValue
+		} else if hasDefault {
+			return defaultValue
+		} else if found {
+			return envValue
+		}
+		i.unset[name] = true
+		return ""
+	})
+}
+
+func (i *interpolator) interpolateAll(values []string) []string {
+	var interpolated []string
+	for _, value := range values {
+		interpolated = append(interpolated, i.interpolate(value))
+	}
+	return interpolated
+}
+
+// interpolateEnv substitutes environment variables in the URLs, paths,
+// headers and security values of the manifest.
+func interpolateEnv(o *OpenDeps) error {
+	i := &interpolator{unset: make(map[string]bool)}
+
+	for depName, dep := range o.Dependencies {
+		dep.Spec = i.interpolate(dep.Spec)
+		if dep.Availability != nil {
+			availability := *dep.Availability
+			availability.Url = i.interpolate(availability.Url)
+			availability.Path = i.interpolate(availability.Path)
+			availability.Security = i.interpolate(availability.Security)
+			if availability.Version != nil {
+				version := *availability.Version
+				version.Url = i.interpolate(version.Url)
+				version.Path = i.interpolate(version.Path)
+				availability.Version = &version
+			}
+			dep.Availability = &availability
+		}
+		o.Dependencies[depName] = dep
+	}
+	if o.Components != nil {
+		for name, securityConfig := range o.Components.SecurityConfigs {
+			securityConfig.Scheme = i.interpolate(securityConfig.Scheme)
+			securityConfig.Headers = i.interpolateAll(securityConfig.Headers)
+			o.Components.SecurityConfigs[name] = securityConfig
+		}
+	}
+	for envName, environment := range o.Environments {
+		for depName, server := range environment.Servers {
+			server.Url = i.interpolate(server.Url)
+			for name, value := range server.Variables {
+				server.Variables[name] = i.interpolate(value)
+			}
+			environment.Servers[depName] = server
+		}
+		o.Environments[envName] = environment
+	}
+
+	if len(i.unset) > 0 {
+		var names []string
+		for name := range i.unset {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if strictEnv {
+			return fmt.Errorf("environment variables referenced in manifest are not set: %v", strings.Join(names, ", "))
+		}
+		logrus.Warnf("environment variables referenced in manifest are not set - using empty values: %v", strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Setenv("SVC_URL", "https://svc.example.com")
+	t.Setenv("SVC_VERSION_PATH", "/version")
+
+	tests := []struct {
+		name         string
+		availability *Availability
+		want         *Availability
+	}{
+		{
+			name:         "availability url and path",
+			availability: &Availability{Url: "${SVC_URL}/health", Path: "${MISSING:-/healthz}"},
+			want:         &Availability{Url: "https://svc.example.com/health", Path: "/healthz"},
+		},
+		{
+			name: "version endpoint url and path",
+			availability: &Availability{
+				Path:    "/health",
+				Version: &VersionEndpoint{Url: "${SVC_URL}/version", Path: "${SVC_VERSION_PATH}", Field: "build.version"},
+			},
+			want: &Availability{
+				Path:    "/health",
+				Version: &VersionEndpoint{Url: "https://svc.example.com/version", Path: "/version", Field: "build.version"},
+			},
+		},
+		{
+			name:         "escaped dollar",
+			availability: &Availability{Path: "/$$health"},
+			want:         &Availability{Path: "/$health"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &OpenDeps{Dependencies: map[string]Dependency{"svc": {Availability: tt.availability}}}
+			if err := interpolateEnv(o); err != nil {
+				t.Fatalf("interpolateEnv() error = %v", err)
+			}
+			if got := o.Dependencies["svc"].Availability; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("interpolateEnv() availability = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -35,6 +35,26 @@ type Availability struct {
 	Url      string `yaml:",omitempty"`
 	Path     string `yaml:",omitempty"`
 	Security string `yaml:",omitempty"`
+
+	// Check is how the availability of a gRPC dependency is checked: 'health'
+	// (the default) or 'reflection', or that of a GraphQL dependency:
+	// 'typename' (the default) or 'introspection'
+	Check string `yaml:"x-check,omitempty"`
+
+	// Version is the endpoint returning the deployed version of the dependency
+	Version *VersionEndpoint `yaml:"x-version,omitempty"`
+}
+
+// VersionEndpoint returns the deployed version of a dependency, which is
+// checked against the dependency's version constraint.
+type VersionEndpoint struct {
+	Url  string `yaml:",omitempty"`
+	Path string `yaml:",omitempty"`
+
+	// Field is the dot-separated path of the version in a JSON response,
+	// such as 'build.version'. If empty, the 'version' field of a JSON
+	// response, or the whole response, is used.
+	Field string `yaml:",omitempty"`
 }
 
 type Dependency struct {
@@ -44,6 +64,19 @@ type Dependency struct {
 	Version      string        `yaml:",omitempty"`
 	Required     bool          `yaml:",omitempty"`
 	Availability *Availability `yaml:",omitempty"`
+	Contact      *Contact      `yaml:"x-contact,omitempty"`
+
+	// Channels used by the dependent, for dependencies with an AsyncAPI spec
+	Channels []string `yaml:"x-channels,omitempty"`
+
+	// Services and methods used by the dependent, for dependencies with a gRPC spec
+	Services []string `yaml:"x-services,omitempty"`
+
+	// Examples of gRPC responses, keyed by method, for mocks
+	Examples map[string]interface{} `yaml:"x-examples,omitempty"`
+
+	// Query documents used by the dependent, for dependencies with a GraphQL spec
+	Queries []string `yaml:"x-queries,omitempty"`
 }
 
 type SecurityConfig struct {
@@ -56,9 +89,33 @@ type Components struct {
 	SecurityConfigs map[string]SecurityConfig
 }
 
+// EnvironmentServer determines the base URL of a dependency in an environment,
+// either directly, or by selecting one of the servers in its spec.
+type EnvironmentServer struct {
+	Url         string            `yaml:",omitempty"`
+	Description string            `yaml:",omitempty"`
+	Variables   map[string]string `yaml:",omitempty"`
+}
+
+// UnmarshalYAML allows an EnvironmentServer to be written as just its URL.
+func (s *EnvironmentServer) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var url string
+	if err := unmarshal(&url); err == nil {
+		s.Url = url
+		return nil
+	}
+	type plain EnvironmentServer
+	return unmarshal((*plain)(s))
+}
+
+type Environment struct {
+	Servers map[string]EnvironmentServer `yaml:",omitempty"`
+}
+
 type OpenDeps struct {
 	OpenDeps     string
 	Info         *Info
 	Dependencies map[string]Dependency
-	Components   *Components `yaml:",omitempty"`
+	Components   *Components            `yaml:",omitempty"`
+	Environments map[string]Environment `yaml:"x-environments,omitempty"`
 }
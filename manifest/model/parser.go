@@ -17,24 +17,39 @@ limitations under the License.
 package model
 
 import (
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
-	"io/ioutil"
 )
 
 func Parse(manifestPath string) *OpenDeps {
-	raw, err := ioutil.ReadFile(manifestPath)
+	o, err := Load(manifestPath)
 	if err != nil {
 		logrus.Fatalln(err)
 	}
+	return o
+}
+
+// Load reads the manifest, after resolving the manifests it extends and any
+// overlays, and interpolating environment variables.
+func Load(manifestPath string) (*OpenDeps, error) {
+	raw, err := ResolveRaw(manifestPath)
+	if err != nil {
+		return nil, err
+	}
 
 	o := OpenDeps{}
 
 	err = yaml.Unmarshal([]byte(raw), &o)
 	if err != nil {
-		logrus.Fatalf("error: %v\n", err)
+		return nil, fmt.Errorf("error: %v\n", err)
+	}
+
+	err = interpolateEnv(&o)
+	if err != nil {
+		return nil, fmt.Errorf("error: %v: %v\n", manifestPath, err)
 	}
 
 	logrus.Tracef("opendeps parsed:\n%v\n\n", o)
-	return &o
+	return &o, nil
 }
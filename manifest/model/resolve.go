@@ -0,0 +1,243 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"opendeps.org/opendeps/fileutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// extendsKey is the key naming the manifest that a manifest extends.
+const extendsKey = "extends"
+
+// overlayEnvironment is the name of the environment whose overlay file, if
+// present next to the manifest, is applied
+var overlayEnvironment string
+
+// overlayFiles are applied, in order, after any environment overlay
+var overlayFiles []string
+
+// ConfigureOverlays sets the environment whose overlay file is applied to
+// manifests, if it exists, and additional overlay files to apply after it.
+func ConfigureOverlays(environment string, files []string) {
+	overlayEnvironment = environment
+	overlayFiles = files
+}
+
+// GetEnvironmentOverlayPath returns the path of the overlay file for the
+// environment, such as opendeps.staging.yaml for opendeps.yaml, and
+// whether it exists.
+func GetEnvironmentOverlayPath(manifestPath string, environment string) (string, bool) {
+	ext := filepath.Ext(manifestPath)
+	overlayPath := strings.TrimSuffix(manifestPath, ext) + "." + environment + ext
+	_, err := os.Stat(overlayPath)
+	return overlayPath, err == nil
+}
+
+// GetOverlayPaths returns the paths of the overlay files that are applied
+// to the manifest, in the order they are applied.
+func GetOverlayPaths(manifestPath string) ([]string, error) {
+	var overlays []string
+	if overlayEnvironment != "" {
+		if overlayPath, found := GetEnvironmentOverlayPath(manifestPath, overlayEnvironment); found {
+			overlays = append(overlays, overlayPath)
+		}
+	}
+	for _, overlayFile := range overlayFiles {
+		overlayPath, err := filepath.Abs(overlayFile)
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, overlayPath)
+	}
+	return overlays, nil
+}
+
+// ResolveSources returns the locations of the files that the manifest is
+// resolved from, in order of precedence: its overlays, last applied first,
+// then the manifest itself, then the manifests it extends, closest first.
+func ResolveSources(manifestPath string) ([]string, error) {
+	overlays, err := GetOverlayPaths(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for i := len(overlays) - 1; i >= 0; i-- {
+		sources = append(sources, overlays[i])
+	}
+
+	location := manifestPath
+	for {
+		for _, visited := range sources {
+			if visited == location {
+				return nil, fmt.Errorf("circular %v: %v", extendsKey, location)
+			}
+		}
+		sources = append(sources, location)
+
+		doc, err := loadDocument(location, manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		parent, ok := doc[extendsKey].(string)
+		if !ok || parent == "" {
+			return sources, nil
+		}
+		if location, err = fileutil.ResolveLocation(location, parent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ResolveRaw returns the content of the manifest after the manifests it
+// extends, and any overlays, have been merged into it.
+func ResolveRaw(manifestPath string) ([]byte, error) {
+	doc, err := loadExtended(manifestPath, manifestPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	overlays, err := GetOverlayPaths(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, overlayPath := range overlays {
+		logrus.Debugf("applying overlay: %v", overlayPath)
+		overlay, err := loadDocument(overlayPath, manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := overlay[extendsKey]; found {
+			return nil, fmt.Errorf("overlay [%v] cannot use %v", overlayPath, extendsKey)
+		}
+		doc = mergePatch(doc, overlay).(map[interface{}]interface{})
+	}
+
+	return yaml.Marshal(doc)
+}
+
+// loadExtended loads the manifest at location and merges it over
+// the manifest it extends, if any, recursively.
+func loadExtended(location string, manifestPath string, chain []string) (map[interface{}]interface{}, error) {
+	for _, visited := range chain {
+		if visited == location {
+			return nil, fmt.Errorf("circular %v: %v", extendsKey, strings.Join(append(chain, location), " -> "))
+		}
+	}
+
+	doc, err := loadDocument(location, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	extends, found := doc[extendsKey]
+	if !found {
+		return doc, nil
+	}
+	delete(doc, extendsKey)
+
+	parent, ok := extends.(string)
+	if !ok || parent == "" {
+		return nil, fmt.Errorf("invalid %v in [%v]: must be a path or URL", extendsKey, location)
+	}
+	parentLocation, err := fileutil.ResolveLocation(location, parent)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Debugf("manifest [%v] extends: %v", location, parentLocation)
+
+	base, err := loadExtended(parentLocation, manifestPath, append(chain, location))
+	if err != nil {
+		return nil, err
+	}
+	return mergePatch(base, doc).(map[interface{}]interface{}), nil
+}
+
+// loadDocument reads a manifest or overlay, making the relative spec
+// locations of its dependencies relative to the root manifest instead.
+func loadDocument(location string, manifestPath string) (map[interface{}]interface{}, error) {
+	raw, err := fileutil.ReadAllContent(location)
+	if err != nil {
+		return nil, err
+	}
+	doc := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing [%v]: %v", location, err)
+	}
+	if location != manifestPath {
+		if err := rebaseSpecs(doc, location, manifestPath); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func rebaseSpecs(doc map[interface{}]interface{}, location string, manifestPath string) error {
+	dependencies, _ := doc["dependencies"].(map[interface{}]interface{})
+	for _, d := range dependencies {
+		dependency, ok := d.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		spec, ok := dependency["spec"].(string)
+		if !ok || !(strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../")) {
+			continue
+		}
+		specLocation, err := fileutil.ResolveLocation(location, spec)
+		if err != nil {
+			return err
+		}
+		if !fileutil.IsRemote(specLocation) {
+			if relPath, err := filepath.Rel(filepath.Dir(manifestPath), specLocation); err == nil {
+				specLocation = "./" + filepath.ToSlash(relPath)
+			}
+		}
+		dependency["spec"] = specLocation
+	}
+	return nil
+}
+
+// mergePatch applies patch to target following the rules of JSON Merge Patch
+// (RFC 7386): mappings are merged recursively, a null value removes the key
+// from the target, and any other value replaces the target value.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[interface{}]interface{})
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[interface{}]interface{})
+	if !ok {
+		targetMap = make(map[interface{}]interface{})
+	}
+
+	merged := make(map[interface{}]interface{}, len(targetMap))
+	for k, v := range targetMap {
+		merged[k] = v
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = mergePatch(merged[k], v)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,228 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{
+			name:   "adds keys",
+			target: "a: 1",
+			patch:  "b: 2",
+			want:   "{a: 1, b: 2}",
+		},
+		{
+			name:   "merges maps recursively",
+			target: "deps: {foo: {spec: foo.yaml, required: true}}",
+			patch:  "deps: {foo: {required: false}, bar: {spec: bar.yaml}}",
+			want:   "deps: {foo: {spec: foo.yaml, required: false}, bar: {spec: bar.yaml}}",
+		},
+		{
+			name:   "null removes key",
+			target: "deps: {foo: {spec: foo.yaml}, bar: {spec: bar.yaml}}",
+			patch:  "deps: {bar: null}",
+			want:   "deps: {foo: {spec: foo.yaml}}",
+		},
+		{
+			name:   "replaces lists",
+			target: "channels: [a, b]",
+			patch:  "channels: [c]",
+			want:   "channels: [c]",
+		},
+		{
+			name:   "replaces scalar with map",
+			target: "info: none",
+			patch:  "info: {title: foo}",
+			want:   "info: {title: foo}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergePatch(unmarshalMap(t, tt.target), unmarshalMap(t, tt.patch))
+			if want := unmarshalMap(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("mergePatch() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResolveRaw(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		overlays []string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "extends base manifest",
+			files: map[string]string{
+				"opendeps.yaml":      "extends: base/opendeps.yaml\ninfo: {title: child}",
+				"base/opendeps.yaml": "info: {title: base, version: 1.0.0}\ndependencies: {foo: {spec: ./foo.yaml}}",
+			},
+			want: "info: {title: child, version: 1.0.0}\ndependencies: {foo: {spec: ./base/foo.yaml}}",
+		},
+		{
+			name: "applies overlays in order",
+			files: map[string]string{
+				"opendeps.yaml": "dependencies: {foo: {required: true}, bar: {required: true}}",
+				"one.yaml":      "dependencies: {foo: {required: false}}",
+				"two.yaml":      "dependencies: {bar: null}",
+			},
+			overlays: []string{"one.yaml", "two.yaml"},
+			want:     "dependencies: {foo: {required: false}}",
+		},
+		{
+			name: "rejects circular extends",
+			files: map[string]string{
+				"opendeps.yaml": "extends: other.yaml",
+				"other.yaml":    "extends: opendeps.yaml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "rejects extends in overlay",
+			files: map[string]string{
+				"opendeps.yaml": "info: {title: foo}",
+				"overlay.yaml":  "extends: opendeps.yaml",
+			},
+			overlays: []string{"overlay.yaml"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var overlays []string
+			for _, overlay := range tt.overlays {
+				overlays = append(overlays, filepath.Join(dir, overlay))
+			}
+			ConfigureOverlays("", overlays)
+			defer ConfigureOverlays("", nil)
+
+			raw, err := ResolveRaw(filepath.Join(dir, "opendeps.yaml"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRaw() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, want := unmarshalMap(t, string(raw)), unmarshalMap(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("ResolveRaw() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResolveSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		overlays []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:  "single manifest",
+			files: map[string]string{"opendeps.yaml": "info: {title: foo}"},
+			want:  []string{"opendeps.yaml"},
+		},
+		{
+			name: "overlays, last first, then extended manifests, closest first",
+			files: map[string]string{
+				"opendeps.yaml":      "extends: base/opendeps.yaml",
+				"base/opendeps.yaml": "extends: ../root.yaml",
+				"root.yaml":          "info: {title: root}",
+				"one.yaml":           "info: {title: one}",
+				"two.yaml":           "info: {title: two}",
+			},
+			overlays: []string{"one.yaml", "two.yaml"},
+			want:     []string{"two.yaml", "one.yaml", "opendeps.yaml", "base/opendeps.yaml", "root.yaml"},
+		},
+		{
+			name: "circular extends",
+			files: map[string]string{
+				"opendeps.yaml": "extends: base.yaml",
+				"base.yaml":     "extends: opendeps.yaml",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var overlays []string
+			for _, overlay := range tt.overlays {
+				overlays = append(overlays, filepath.Join(dir, overlay))
+			}
+			ConfigureOverlays("", overlays)
+			defer ConfigureOverlays("", nil)
+
+			sources, err := ResolveSources(filepath.Join(dir, "opendeps.yaml"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, source := range sources {
+				relative, _ := filepath.Rel(dir, source)
+				got = append(got, filepath.ToSlash(relative))
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveSources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func unmarshalMap(t *testing.T, content string) map[interface{}]interface{} {
+	doc := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/spechandlers"
+	k8syaml "sigs.k8s.io/yaml"
+	"sort"
+)
+
+// dependencyDefaults are set on each dependency that omits them,
+// so that expressions need not check for their presence.
+var dependencyDefaults = map[string]interface{}{
+	"summary":     "",
+	"description": "",
+	"spec":        "",
+	"version":     "",
+	"required":    false,
+}
+
+// Input is the data against which policies are evaluated: the manifest,
+// keyed as in the manifest file, and the metadata of each dependency's spec.
+type Input struct {
+	Manifest map[string]interface{}
+	Specs    map[string]interface{}
+}
+
+// BuildInput converts the manifest for evaluation, and reads the kind,
+// title and version of each dependency's spec.
+func BuildInput(manifestPath string, manifest *model.OpenDeps) (*Input, error) {
+	marshalled, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	j, err := k8syaml.YAMLToJSON(marshalled)
+	if err != nil {
+		return nil, err
+	}
+	input := &Input{Specs: make(map[string]interface{})}
+	if err := json.Unmarshal(j, &input.Manifest); err != nil {
+		return nil, err
+	}
+
+	dependencies, _ := input.Manifest["dependencies"].(map[string]interface{})
+	if dependencies == nil {
+		dependencies = make(map[string]interface{})
+		input.Manifest["dependencies"] = dependencies
+	}
+	for depName, dep := range manifest.Dependencies {
+		if depMap, ok := dependencies[depName].(map[string]interface{}); ok {
+			for key, value := range dependencyDefaults {
+				if _, found := depMap[key]; !found {
+					depMap[key] = value
+				}
+			}
+		}
+		input.Specs[depName] = readSpecMetadata(manifestPath, dep)
+	}
+	return input, nil
+}
+
+// readSpecMetadata returns the kind, location, title and version of the
+// dependency's spec. If the spec cannot be read, the error is included.
+func readSpecMetadata(manifestPath string, dep model.Dependency) map[string]interface{} {
+	location := fileutil.MakeAbsoluteRelativeToFile(dep.Spec, manifestPath)
+	raw, readErr := fileutil.ReadAllContent(location)
+	handler := spechandlers.DetectForContent(location, raw)
+	metadata := map[string]interface{}{
+		"kind":     handler.Kind(),
+		"location": location,
+		"title":    "",
+		"version":  "",
+		"error":    "",
+	}
+	if readErr != nil {
+		metadata["error"] = fmt.Sprint(readErr)
+		return metadata
+	}
+	scaffolded, err := handler.Scaffold(location, raw)
+	if err != nil {
+		metadata["error"] = fmt.Sprint(err)
+		return metadata
+	}
+	metadata["title"] = scaffolded.Summary
+	metadata["version"] = scaffolded.Version
+	return metadata
+}
+
+func (i *Input) dependencyNames() []string {
+	var names []string
+	for name := range i.Specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// activation returns the variables for evaluating an expression for the
+// manifest or, if depName is set, for the dependency.
+func (i *Input) activation(depName string) map[string]interface{} {
+	activation := map[string]interface{}{
+		"manifest": i.Manifest,
+		"specs":    i.Specs,
+	}
+	if depName != "" {
+		activation["name"] = depName
+		activation["dependency"] = i.Manifest["dependencies"].(map[string]interface{})[depName]
+		activation["spec"] = i.Specs[depName]
+	}
+	return activation
+}
@@ -0,0 +1,253 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/sirupsen/logrus"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"opendeps.org/opendeps/manifest/severity"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ScopeManifest policies are evaluated once for the manifest
+	ScopeManifest = "manifest"
+
+	// ScopeDependency policies are evaluated for each dependency
+	ScopeDependency = "dependency"
+)
+
+// Policy is a rule, written as a CEL expression, that a manifest must satisfy.
+type Policy struct {
+	Id          string
+	Description string
+	Severity    severity.Severity
+	Scope       string
+
+	// Expression must evaluate to true for the manifest, or each
+	// dependency, to satisfy the policy
+	Expression string
+
+	// Message is reported when the policy is violated; if empty,
+	// the description is used
+	Message string
+
+	// Source is the file declaring the policy
+	Source string `yaml:"-"`
+
+	program cel.Program
+}
+
+// Violation is a failure of a manifest, or one of its dependencies, to
+// satisfy a policy.
+type Violation struct {
+	PolicyId   string            `json:"policy"`
+	Severity   severity.Severity `json:"severity"`
+	Dependency string            `json:"dependency,omitempty"`
+	Message    string            `json:"message"`
+	Source     string            `json:"source"`
+}
+
+type policyFile struct {
+	Policies []Policy
+}
+
+// Load reads and compiles the policies in the files at the given paths. For
+// paths that are directories, the YAML files they contain are read.
+func Load(paths []string) ([]Policy, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policies: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(matches)
+			files = append(files, matches...)
+		}
+	}
+
+	var policies []Policy
+	ids := make(map[string]string)
+	for _, file := range files {
+		loaded, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range loaded {
+			if source, found := ids[policy.Id]; found {
+				return nil, fmt.Errorf("policy [%v] in %v is also declared in %v", policy.Id, file, source)
+			}
+			ids[policy.Id] = file
+			policies = append(policies, policy)
+		}
+	}
+	logrus.Debugf("loaded %d policies from %d file(s)", len(policies), len(files))
+	return policies, nil
+}
+
+func loadFile(file string) ([]Policy, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file [%v]: %v", file, err)
+	}
+	parsed := policyFile{}
+	if err := yaml.UnmarshalStrict(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file [%v]: %v", file, err)
+	}
+
+	for i := range parsed.Policies {
+		policy := &parsed.Policies[i]
+		policy.Source = file
+		if policy.Id == "" {
+			return nil, fmt.Errorf("policy %d in %v has no id", i+1, file)
+		}
+		if policy.Severity == "" {
+			policy.Severity = severity.Error
+		} else if policy.Severity, err = severity.Parse(string(policy.Severity)); err != nil {
+			return nil, fmt.Errorf("policy [%v] in %v: %v", policy.Id, file, err)
+		}
+		if policy.Scope == "" {
+			policy.Scope = ScopeManifest
+		}
+		if err := policy.compile(); err != nil {
+			return nil, fmt.Errorf("policy [%v] in %v: %v", policy.Id, file, err)
+		}
+	}
+	return parsed.Policies, nil
+}
+
+// compile checks the expression, which must evaluate to a bool,
+// and prepares it for evaluation.
+func (p *Policy) compile() error {
+	if strings.TrimSpace(p.Expression) == "" {
+		return fmt.Errorf("no expression")
+	}
+	env, err := newEnv(p.Scope)
+	if err != nil {
+		return err
+	}
+	ast, issues := env.Compile(p.Expression)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("invalid expression: %v", issues.Err())
+	}
+	if !proto.Equal(ast.ResultType(), decls.Bool) && !proto.Equal(ast.ResultType(), decls.Dyn) {
+		return fmt.Errorf("expression must evaluate to a bool")
+	}
+	p.program, err = env.Program(ast)
+	return err
+}
+
+// newEnv declares the variables available to expressions in the scope.
+func newEnv(scope string) (*cel.Env, error) {
+	declarations := []*exprpb.Decl{
+		decls.NewVar("manifest", decls.Dyn),
+		decls.NewVar("specs", decls.NewMapType(decls.String, decls.Dyn)),
+	}
+	switch scope {
+	case ScopeManifest:
+	case ScopeDependency:
+		declarations = append(declarations,
+			decls.NewVar("name", decls.String),
+			decls.NewVar("dependency", decls.Dyn),
+			decls.NewVar("spec", decls.Dyn),
+		)
+	default:
+		return nil, fmt.Errorf("invalid scope [%v] - must be one of: %v, %v", scope, ScopeManifest, ScopeDependency)
+	}
+	return cel.NewEnv(cel.Declarations(declarations...))
+}
+
+// Evaluate returns the violations of the policies by the input.
+// Policies that cannot be evaluated are returned as errors.
+func Evaluate(policies []Policy, input *Input) ([]Violation, []error) {
+	var violations []Violation
+	var errs []error
+	for _, policy := range policies {
+		if policy.Scope == ScopeManifest {
+			violation, err := policy.evaluate(input.activation(""), "")
+			if err != nil {
+				errs = append(errs, err)
+			} else if violation != nil {
+				violations = append(violations, *violation)
+			}
+			continue
+		}
+		for _, depName := range input.dependencyNames() {
+			violation, err := policy.evaluate(input.activation(depName), depName)
+			if err != nil {
+				errs = append(errs, err)
+			} else if violation != nil {
+				violations = append(violations, *violation)
+			}
+		}
+	}
+	return violations, errs
+}
+
+func (p *Policy) evaluate(activation map[string]interface{}, depName string) (*Violation, error) {
+	subject := "manifest"
+	if depName != "" {
+		subject = fmt.Sprintf("dependency [%v]", depName)
+	}
+	out, _, err := p.program.Eval(activation)
+	if err != nil {
+		return nil, fmt.Errorf("policy [%v] could not be evaluated for %v: %v", p.Id, subject, err)
+	}
+	satisfied, isBool := out.Value().(bool)
+	if !isBool {
+		return nil, fmt.Errorf("policy [%v] evaluated to %v, not a bool, for %v", p.Id, out.Value(), subject)
+	}
+	if satisfied {
+		return nil, nil
+	}
+
+	message := p.Message
+	if message == "" {
+		message = p.Description
+	}
+	if message == "" {
+		message = "policy is violated"
+	}
+	if depName != "" {
+		message = fmt.Sprintf("dependency [%v]: %v", depName, message)
+	}
+	return &Violation{
+		PolicyId:   p.Id,
+		Severity:   p.Severity,
+		Dependency: depName,
+		Message:    message,
+		Source:     p.Source,
+	}, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"opendeps.org/opendeps/manifest/severity"
+	"reflect"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	input := &Input{
+		Manifest: map[string]interface{}{
+			"dependencies": map[string]interface{}{
+				"billing":        map[string]interface{}{"required": true, "version": "1.0.0"},
+				"legacy-billing": map[string]interface{}{"required": false, "version": ""},
+			},
+		},
+		Specs: map[string]interface{}{
+			"billing":        map[string]interface{}{"kind": "OpenAPI", "version": "1.0.0"},
+			"legacy-billing": map[string]interface{}{"kind": "gRPC", "version": ""},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		policy         Policy
+		wantViolations []Violation
+		wantErrs       int
+	}{
+		{
+			name:   "satisfied manifest policy",
+			policy: Policy{Id: "few-deps", Expression: "manifest.dependencies.size() <= 2"},
+		},
+		{
+			name:   "violated manifest policy",
+			policy: Policy{Id: "one-dep", Expression: "manifest.dependencies.size() <= 1", Message: "too many"},
+			wantViolations: []Violation{
+				{PolicyId: "one-dep", Severity: severity.Error, Message: "too many"},
+			},
+		},
+		{
+			name:   "violated dependency policy uses description",
+			policy: Policy{Id: "no-legacy", Scope: ScopeDependency, Severity: severity.Warning, Expression: `name != "legacy-billing"`, Description: "no legacy"},
+			wantViolations: []Violation{
+				{PolicyId: "no-legacy", Severity: severity.Warning, Dependency: "legacy-billing", Message: "dependency [legacy-billing]: no legacy"},
+			},
+		},
+		{
+			name:   "dependency policy reads spec metadata",
+			policy: Policy{Id: "versioned", Scope: ScopeDependency, Expression: `spec.kind != "OpenAPI" || dependency.version != ""`},
+		},
+		{
+			name:     "non-bool result is an error",
+			policy:   Policy{Id: "dyn", Scope: ScopeDependency, Expression: "dependency.version"},
+			wantErrs: 2,
+		},
+		{
+			name:     "missing key is an error",
+			policy:   Policy{Id: "missing", Expression: "manifest.info.title == 'x'"},
+			wantErrs: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			if policy.Severity == "" {
+				policy.Severity = severity.Error
+			}
+			if policy.Scope == "" {
+				policy.Scope = ScopeManifest
+			}
+			if err := policy.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+
+			violations, errs := Evaluate([]Policy{policy}, input)
+			if len(errs) != tt.wantErrs {
+				t.Errorf("Evaluate() errors = %v, want %d", errs, tt.wantErrs)
+			}
+			if !reflect.DeepEqual(violations, tt.wantViolations) {
+				t.Errorf("Evaluate() violations = %+v, want %+v", violations, tt.wantViolations)
+			}
+		})
+	}
+}
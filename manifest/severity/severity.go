@@ -0,0 +1,52 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package severity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is how severe a problem with a manifest is, such as
+// a lint finding or a policy violation.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Note    Severity = "note"
+)
+
+var ranks = map[Severity]int{
+	Note:    1,
+	Warning: 2,
+	Error:   3,
+}
+
+// Parse returns the severity with the given name.
+func Parse(name string) (Severity, error) {
+	severity := Severity(strings.ToLower(name))
+	if _, found := ranks[severity]; !found {
+		return "", fmt.Errorf("invalid severity [%v] - must be one of: error, warning, note", name)
+	}
+	return severity, nil
+}
+
+// AtLeast determines whether the severity is the same as, or more severe than, other.
+func (s Severity) AtLeast(other Severity) bool {
+	return ranks[s] >= ranks[other]
+}
@@ -0,0 +1,325 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vendoring
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"opendeps.org/opendeps/spechandlers"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const VendorDirName = "opendeps_vendor"
+const IndexFileName = "vendor.yaml"
+
+type VendoredFile struct {
+	Source string `yaml:"source"`
+	Path   string `yaml:"path"`
+	Digest string `yaml:"digest"`
+}
+
+type VendoredDependency struct {
+	Spec  string         `yaml:"spec"`
+	Files []VendoredFile `yaml:"files"`
+}
+
+// Index records the origin of each vendored file.
+type Index struct {
+	Dependencies map[string]VendoredDependency `yaml:"dependencies"`
+}
+
+// fetchedFile is a spec document retrieved from its source location.
+type fetchedFile struct {
+	location string
+	path     string
+	raw      []byte
+}
+
+// GetVendorDir returns the vendor directory for the given manifest,
+// which is always adjacent to the manifest.
+func GetVendorDir(manifestPath string) string {
+	return filepath.Join(filepath.Dir(manifestPath), VendorDirName)
+}
+
+// Vendor downloads the spec of each dependency, and every document it
+// references, into the vendor directory, replacing any existing content.
+func Vendor(manifestPath string, manifest *model.OpenDeps) (*Index, error) {
+	vendorDir := GetVendorDir(manifestPath)
+	index := &Index{
+		Dependencies: make(map[string]VendoredDependency),
+	}
+
+	fetched := make(map[string][]fetchedFile)
+	for depName, dep := range manifest.Dependencies {
+		files, err := fetchDependency(manifestPath, depName, dep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to vendor dependency [%v]: %v", depName, err)
+		}
+		fetched[depName] = files
+	}
+
+	if err := os.RemoveAll(vendorDir); err != nil {
+		return nil, fmt.Errorf("failed to remove vendor dir [%v]: %v", vendorDir, err)
+	}
+	for depName, files := range fetched {
+		vendored := VendoredDependency{
+			Spec: manifest.Dependencies[depName].Spec,
+		}
+		for _, file := range files {
+			destPath := filepath.Join(vendorDir, filepath.FromSlash(file.path))
+			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory for [%v]: %v", destPath, err)
+			}
+			if err := ioutil.WriteFile(destPath, file.raw, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write vendored file [%v]: %v", destPath, err)
+			}
+			logrus.Debugf("vendored %v to %v", file.location, destPath)
+
+			vendored.Files = append(vendored.Files, VendoredFile{
+				Source: makePortableLocation(file.location, manifestPath),
+				Path:   file.path,
+				Digest: digest(file.raw),
+			})
+		}
+		index.Dependencies[depName] = vendored
+	}
+
+	if err := writeIndex(index, filepath.Join(vendorDir, IndexFileName)); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// fetchDependency retrieves the spec for the dependency and, recursively,
+// all documents referenced from it.
+func fetchDependency(manifestPath string, depName string, dep model.Dependency) ([]fetchedFile, error) {
+	rootLocation := fileutil.MakeAbsoluteRelativeToFile(dep.Spec, manifestPath)
+
+	var files []fetchedFile
+	visited := make(map[string]bool)
+	pending := []string{rootLocation}
+	for len(pending) > 0 {
+		location := pending[0]
+		pending = pending[1:]
+		if visited[location] {
+			continue
+		}
+		visited[location] = true
+
+		raw, err := fileutil.ReadAllContent(location)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fetchedFile{
+			location: location,
+			path:     buildVendoredPath(depName, rootLocation, location),
+			raw:      raw,
+		})
+
+		refs, err := spechandlers.DetectForContent(location, raw).FindExternalRefs(location, raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse [%v]: %v", location, err)
+		}
+		for _, ref := range refs {
+			refLocation, err := fileutil.ResolveLocation(location, ref)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, refLocation)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files, nil
+}
+
+// buildVendoredPath determines the path of a vendored file relative to the
+// vendor directory. Files alongside or beneath the dependency's root spec keep
+// their relative layout; others are placed in an `_external` directory.
+func buildVendoredPath(depName string, rootLocation string, location string) string {
+	if fileutil.IsRemote(rootLocation) == fileutil.IsRemote(location) {
+		var relPath string
+		if fileutil.IsRemote(location) {
+			rootDir := rootLocation[:strings.LastIndex(rootLocation, "/")+1]
+			if strings.HasPrefix(location, rootDir) {
+				relPath = strings.TrimPrefix(location, rootDir)
+			}
+		} else if rel, err := filepath.Rel(filepath.Dir(rootLocation), location); err == nil {
+			relPath = filepath.ToSlash(rel)
+		}
+		relPath = stripQuery(relPath)
+		if relPath != "" && !strings.HasPrefix(relPath, "../") {
+			return path.Join(depName, relPath)
+		}
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(location)))[:12]
+	return path.Join(depName, "_external", hash, path.Base(stripQuery(filepath.ToSlash(location))))
+}
+
+func stripQuery(location string) string {
+	if idx := strings.IndexAny(location, "?#"); idx >= 0 {
+		return location[:idx]
+	}
+	return location
+}
+
+// makePortableLocation keeps local paths relative to the manifest, so the
+// index is portable between machines.
+func makePortableLocation(location string, manifestPath string) string {
+	if fileutil.IsRemote(location) {
+		return location
+	}
+	relPath, err := filepath.Rel(filepath.Dir(manifestPath), location)
+	if err != nil {
+		return location
+	}
+	return "./" + filepath.ToSlash(relPath)
+}
+
+func digest(raw []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(raw))
+}
+
+// LoadIndex reads the vendor index for the given manifest. If the
+// manifest has no vendor directory, nil is returned without an error.
+func LoadIndex(manifestPath string) (*Index, error) {
+	indexPath := filepath.Join(GetVendorDir(manifestPath), IndexFileName)
+	raw, err := ioutil.ReadFile(indexPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read vendor index [%v]: %v", indexPath, err)
+	}
+	index := &Index{}
+	if err := yaml.Unmarshal(raw, index); err != nil {
+		return nil, fmt.Errorf("failed to parse vendor index [%v]: %v", indexPath, err)
+	}
+	return index, nil
+}
+
+func writeIndex(index *Index, indexPath string) error {
+	marshalled, err := yaml.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vendor index: %v", err)
+	}
+	header := "# This file is generated by 'opendeps vendor'. Do not edit it by hand.\n"
+	err = ioutil.WriteFile(indexPath, append([]byte(header), marshalled...), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write vendor index [%v]: %v", indexPath, err)
+	}
+	return nil
+}
+
+// ApplyOverlay causes the vendored copies of dependency specs to be
+// read in place of their sources, if the manifest has a vendor directory.
+// Dependencies whose spec has changed since vendoring are not overlaid.
+func ApplyOverlay(manifestPath string, manifest *model.OpenDeps) error {
+	index, err := LoadIndex(manifestPath)
+	if err != nil {
+		return err
+	} else if index == nil {
+		return nil
+	}
+
+	vendorDir := GetVendorDir(manifestPath)
+	for depName, dep := range manifest.Dependencies {
+		vendored, found := index.Dependencies[depName]
+		if !found || vendored.Spec != dep.Spec {
+			logrus.Warnf("dependency [%v] is not vendored or has changed since vendoring - using its source", depName)
+			continue
+		}
+		for _, file := range vendored.Files {
+			source := fileutil.MakeAbsoluteRelativeToFile(file.Source, manifestPath)
+			fileutil.RegisterOverlay(source, filepath.Join(vendorDir, filepath.FromSlash(file.Path)))
+		}
+	}
+	logrus.Debugf("using vendored specs from: %v", vendorDir)
+	return nil
+}
+
+// Check compares the vendored files against their sources, returning a
+// description of each difference. Callers should not apply the overlay
+// before checking, otherwise the vendored copies are compared with themselves.
+func Check(manifestPath string, manifest *model.OpenDeps) ([]string, error) {
+	index, err := LoadIndex(manifestPath)
+	if err != nil {
+		return nil, err
+	} else if index == nil {
+		return nil, fmt.Errorf("no vendor directory found at: %v", GetVendorDir(manifestPath))
+	}
+
+	var drift []string
+	vendorDir := GetVendorDir(manifestPath)
+	for depName, dep := range manifest.Dependencies {
+		vendored, found := index.Dependencies[depName]
+		if !found {
+			drift = append(drift, fmt.Sprintf("%v: not vendored", depName))
+			continue
+		} else if vendored.Spec != dep.Spec {
+			drift = append(drift, fmt.Sprintf("%v: spec changed from %v to %v", depName, vendored.Spec, dep.Spec))
+			continue
+		}
+
+		upstream, err := fetchDependency(manifestPath, depName, dep)
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("%v: %v", depName, strings.TrimSpace(err.Error())))
+			continue
+		}
+		upstreamFiles := make(map[string]fetchedFile)
+		for _, file := range upstream {
+			upstreamFiles[file.path] = file
+		}
+
+		for _, file := range vendored.Files {
+			local, err := ioutil.ReadFile(filepath.Join(vendorDir, filepath.FromSlash(file.Path)))
+			if err != nil {
+				drift = append(drift, fmt.Sprintf("%v: vendored file missing: %v", depName, file.Path))
+			} else if digest(local) != file.Digest {
+				drift = append(drift, fmt.Sprintf("%v: vendored file modified locally: %v", depName, file.Path))
+			}
+
+			upstreamFile, found := upstreamFiles[file.Path]
+			if !found {
+				drift = append(drift, fmt.Sprintf("%v: no longer referenced upstream: %v", depName, file.Source))
+			} else if digest(upstreamFile.raw) != file.Digest {
+				drift = append(drift, fmt.Sprintf("%v: changed upstream: %v", depName, file.Source))
+			}
+			delete(upstreamFiles, file.Path)
+		}
+		for _, file := range upstreamFiles {
+			drift = append(drift, fmt.Sprintf("%v: newly referenced upstream: %v", depName, makePortableLocation(file.location, manifestPath)))
+		}
+	}
+	for depName := range index.Dependencies {
+		if _, found := manifest.Dependencies[depName]; !found {
+			drift = append(drift, fmt.Sprintf("%v: vendored but not in manifest", depName))
+		}
+	}
+	sort.Strings(drift)
+	return drift, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package versioning
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"strings"
+)
+
+// defaultVersionField is the field of a JSON response holding the version,
+// if no field is configured.
+const defaultVersionField = "version"
+
+// Satisfies determines whether the version satisfies the semantic version
+// constraint, such as '^2.1' or '>=1.4 <2'. A constraint that is just a
+// version, such as '1.2.3', is only satisfied by that version.
+func Satisfies(constraint string, version string) (bool, error) {
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, fmt.Errorf("version [%v] is not a valid semantic version constraint: %v", constraint, err)
+	}
+	parsed, err := semver.NewVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("version [%v] is not a semantic version: %v", version, err)
+	}
+	return constraints.Check(parsed), nil
+}
+
+// ExtractVersion returns the version in the response body of a version
+// endpoint. If field is set, it is the dot-separated path of the version in
+// a JSON response, such as 'build.version'. Otherwise, the 'version' field of
+// a JSON response is used, or the whole body if it is not JSON.
+func ExtractVersion(body []byte, field string) (string, error) {
+	// numbers are decoded as json.Number, so that a version such as
+	// 1.10 is not read as the float 1.1
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decodeAll(decoder, &doc); err != nil {
+		if field != "" {
+			return "", fmt.Errorf("response is not JSON: %v", err)
+		}
+		return strings.TrimSpace(string(body)), nil
+	}
+	if field == "" {
+		if _, isObject := doc.(map[string]interface{}); !isObject {
+			if version, isString := doc.(string); isString {
+				return version, nil
+			}
+			return strings.TrimSpace(string(body)), nil
+		}
+		field = defaultVersionField
+	}
+
+	value := doc
+	for _, key := range strings.Split(field, ".") {
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			return "", fmt.Errorf("no field [%v] in response", field)
+		}
+		var found bool
+		if value, found = object[key]; !found {
+			return "", fmt.Errorf("no field [%v] in response", field)
+		}
+	}
+	switch version := value.(type) {
+	case string:
+		return version, nil
+	case json.Number:
+		return version.String(), nil
+	default:
+		return "", fmt.Errorf("field [%v] in response is not a version", field)
+	}
+}
+
+// decodeAll decodes the only value read by the decoder, failing if there is
+// anything after it, as json.Unmarshal does.
+func decodeAll(decoder *json.Decoder, v interface{}) error {
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected content after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package versioning
+
+import "testing"
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint string
+		version    string
+		want       bool
+		wantErr    bool
+	}{
+		{name: "exact match", constraint: "1.2.3", version: "1.2.3", want: true},
+		{name: "exact mismatch", constraint: "1.2.3", version: "1.2.4", want: false},
+		{name: "caret range", constraint: "^2.1", version: "2.9.0", want: true},
+		{name: "caret range next major", constraint: "^2.1", version: "3.0.0", want: false},
+		{name: "tilde range", constraint: "~1.4.2", version: "1.4.9", want: true},
+		{name: "compound range", constraint: ">=1.4 <2", version: "1.10.0", want: true},
+		{name: "compound range upper bound", constraint: ">=1.4 <2", version: "2.0.0", want: false},
+		{name: "wildcard", constraint: "*", version: "0.0.1", want: true},
+		{name: "invalid constraint", constraint: "not a constraint", version: "1.0.0", wantErr: true},
+		{name: "invalid version", constraint: "^1", version: "latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Satisfies(tt.constraint, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Satisfies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Satisfies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain text", body: "1.2.3\n", want: "1.2.3"},
+		{name: "JSON string", body: `"1.2.3"`, want: "1.2.3"},
+		{name: "default field", body: `{"version": "1.2.3"}`, want: "1.2.3"},
+		{name: "nested field", body: `{"build": {"version": "2.0.0"}}`, field: "build.version", want: "2.0.0"},
+		{name: "numeric field keeps trailing zero", body: `{"version": 1.10}`, want: "1.10"},
+		{name: "integer field", body: `{"version": 3}`, want: "3"},
+		{name: "missing default field", body: `{"name": "billing"}`, wantErr: true},
+		{name: "missing nested field", body: `{"build": "2.0.0"}`, field: "build.version", wantErr: true},
+		{name: "field is not a version", body: `{"version": {"major": 1}}`, wantErr: true},
+		{name: "field set but not JSON", body: "1.2.3", field: "version", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractVersion([]byte(tt.body), tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExtractVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"opendeps.org/opendeps/manifest/versioning"
+	"strings"
+)
+
+// Check requests the availability endpoint of the dependency and, if it
+// has a version endpoint, checks that the deployed version satisfies the
+// version constraint of the dependency.
+func (openApiHandler) Check(dep DependencyContext) error {
+	availability := dep.Dependency.Availability
+	if availability == nil || (availability.Url == "" && availability.Path == "") {
+		return fmt.Errorf("no availability URL or path for %v", dep.Name)
+	}
+
+	if "" != availability.Security {
+		logrus.Warnf("security configuration for availability endpoints is not supported\n")
+	}
+
+	url, err := buildEndpointUrl(dep, availability.Url, availability.Path, determineBasePath)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to reach availability URL [%v]: %v\n", url, err)
+	} else {
+		logrus.Debugf("checked availability [%v]: %s\n", dep.Dependency.Summary, resp.Status)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("failed to reach availability URL [%v]: %s\n", url, resp.Status)
+		}
+	}
+
+	return CheckDeployedVersion(dep, determineBasePath)
+}
+
+// buildEndpointUrl returns the fully qualified URL if set, otherwise the path
+// relative to the base path of the dependency, or an empty string if neither
+// is set.
+func buildEndpointUrl(dep DependencyContext, url string, path string, basePathOf BasePathFunc) (string, error) {
+	if "" != url {
+		// fully qualified
+		return url, nil
+
+	} else if "" != path {
+		if basePathOf == nil {
+			return "", fmt.Errorf("no base path for [%v] - set a fully qualified URL for %v", path, dep.Name)
+		}
+		basePath, err := basePathOf(dep)
+		if err != nil {
+			return "", err
+		}
+		trimmedBasePath := strings.TrimSuffix(basePath, "/")
+		trimmedPath := strings.TrimPrefix(path, "/")
+		return fmt.Sprintf("%v/%v", trimmedBasePath, trimmedPath), nil
+	}
+	return "", nil
+}
+
+// BasePathFunc returns the URL that relative endpoint paths of a dependency
+// are resolved against.
+type BasePathFunc func(dep DependencyContext) (string, error)
+
+// CheckDeployedVersion fetches the deployed version of the dependency from its
+// version endpoint, if it has one and a version constraint, and checks it
+// satisfies the constraint. A version path is resolved against basePathOf;
+// if basePathOf is nil, the version endpoint must be a fully qualified URL.
+func CheckDeployedVersion(dep DependencyContext, basePathOf BasePathFunc) error {
+	availability := dep.Dependency.Availability
+	if availability == nil || availability.Version == nil || dep.Dependency.Version == "" {
+		return nil
+	}
+	endpoint := availability.Version
+	url, err := buildEndpointUrl(dep, endpoint.Url, endpoint.Path, basePathOf)
+	if err != nil {
+		return err
+	} else if url == "" {
+		return fmt.Errorf("no version URL or path for %v", dep.Name)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to reach version URL [%v]: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to reach version URL [%v]: %s", url, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read version URL [%v]: %v", url, err)
+	}
+
+	deployedVersion, err := versioning.ExtractVersion(body, endpoint.Field)
+	if err != nil {
+		return fmt.Errorf("failed to read version from [%v]: %v", url, err)
+	}
+	satisfied, err := versioning.Satisfies(dep.Dependency.Version, deployedVersion)
+	if err != nil {
+		return fmt.Errorf("failed to check deployed version: %v", err)
+	} else if !satisfied {
+		return fmt.Errorf("deployed version [%v] does not satisfy version constraint [%v]", deployedVersion, dep.Dependency.Version)
+	}
+	logrus.Debugf("deployed version [%v] of %v satisfies version constraint [%v]", deployedVersion, dep.Name, dep.Dependency.Version)
+	return nil
+}
+
+// determineBasePath returns the server URL override of the dependency,
+// if set, otherwise the server URL from its spec, which must be absolute.
+func determineBasePath(dep DependencyContext) (string, error) {
+	if dep.ServerUrl != "" {
+		return dep.ServerUrl, nil
+	}
+	serverUrl, err := resolveSpecServerUrl(dep.SpecPath, dep.EnvServer, dep.Lookup)
+	if err != nil {
+		if dep.EnvServer != nil {
+			return "", fmt.Errorf("failed to determine server for environment: %v", err)
+		}
+		return "", err
+	}
+	if parsed, err := url.Parse(serverUrl); err != nil || !parsed.IsAbs() || parsed.Host == "" {
+		return "", fmt.Errorf("server URL [%v] in spec [%v] is not absolute, such as when a Swagger spec has no host - set an availability url, an environment server URL or a --server override for %v", serverUrl, dep.SpecPath, dep.Name)
+	}
+	logrus.Debugf("determined server [%v] from spec [%v]", serverUrl, dep.SpecPath)
+	return serverUrl, nil
+}
+
+// ValidateVersion returns the problems with the version of the dependency:
+// the version of its spec, if any, must satisfy its version constraint and,
+// if basePathOf is nil, its version endpoint must be a fully qualified URL.
+func ValidateVersion(dep DependencyContext, specVersion string, basePathOf BasePathFunc) []Problem {
+	var problems []Problem
+	if dep.Dependency.Version != "" && specVersion != "" {
+		if satisfied, err := versioning.Satisfies(dep.Dependency.Version, specVersion); err != nil {
+			problems = append(problems, Problemf("/version", "%v", err))
+		} else if !satisfied {
+			problems = append(problems, Problemf("/version", "spec version [%v] does not satisfy version constraint [%v]", specVersion, dep.Dependency.Version))
+		}
+	}
+	if availability := dep.Dependency.Availability; availability != nil && availability.Version != nil {
+		if availability.Version.Url == "" && basePathOf == nil {
+			problems = append(problems, Problemf("/availability/x-version", "version endpoint has no url - a path relative to the server is only supported for OpenAPI dependencies"))
+		}
+		if dep.Dependency.Version == "" {
+			problems = append(problems, Problemf("/availability/x-version", "version endpoint is set but the dependency has no version constraint to check"))
+		}
+	}
+	return problems
+}
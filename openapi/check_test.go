@@ -0,0 +1,81 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetermineBasePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		serverUrl string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "first server in spec",
+			spec: "openapi: 3.0.0\nservers:\n  - url: https://a.example.com\n  - url: https://b.example.com\n",
+			want: "https://a.example.com",
+		},
+		{
+			name: "swagger host",
+			spec: "swagger: \"2.0\"\nhost: example.com\nbasePath: /v1\n",
+			want: "https://example.com/v1",
+		},
+		{
+			name:    "swagger without host",
+			spec:    "swagger: \"2.0\"\nbasePath: /v1\n",
+			wantErr: true,
+		},
+		{
+			name:      "server URL override",
+			spec:      "swagger: \"2.0\"\nbasePath: /v1\n",
+			serverUrl: "http://localhost:8080",
+			want:      "http://localhost:8080",
+		},
+		{
+			name:    "no servers",
+			spec:    "openapi: 3.0.0\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specPath := filepath.Join(t.TempDir(), "spec.yaml")
+			if err := ioutil.WriteFile(specPath, []byte(tt.spec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			dep := DependencyContext{
+				Name:      "pets",
+				SpecPath:  specPath,
+				ServerUrl: tt.serverUrl,
+				Lookup:    func(string) (string, bool) { return "", false },
+			}
+			got, err := determineBasePath(dep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("determineBasePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("determineBasePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
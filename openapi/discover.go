@@ -0,0 +1,58 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"opendeps.org/opendeps/fileutil"
+	"path/filepath"
+)
+
+// DiscoverSpecs returns the paths of the OpenAPI specs in dir
+// that satisfy the options. Files that cannot be parsed are skipped.
+func DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error) {
+	files, err := fileutil.FindFiles(dir, []string{".yaml", ".yml", ".json"}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []string
+	for _, file := range files {
+		specPath := filepath.Join(dir, filepath.FromSlash(file))
+		raw, err := ioutil.ReadFile(specPath)
+		if err != nil {
+			return nil, err
+		}
+		if IsOpenApiSpec(raw) {
+			specs = append(specs, specPath)
+		} else {
+			logrus.Tracef("skipping file that is not an OpenAPI spec: %v", specPath)
+		}
+	}
+	return specs, nil
+}
+
+// IsOpenApiSpec determines whether the raw content is an OpenAPI or Swagger spec.
+func IsOpenApiSpec(raw []byte) bool {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return false
+	}
+	return doc["openapi"] != nil || doc["swagger"] != nil
+}
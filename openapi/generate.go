@@ -1,45 +1,133 @@
 package openapi
 
 import (
+	"fmt"
 	imposterfileutil "gatehill.io/imposter/fileutil"
 	"gatehill.io/imposter/impostermodel"
 	"github.com/sirupsen/logrus"
-	"opendeps.org/opendeps/fileutil"
-	"opendeps.org/opendeps/manifest/model"
-	"os"
+	"io/ioutil"
 	"path/filepath"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
 )
 
-func BundleSpecs(stagingDir string, manifestPath string, manifest *model.OpenDeps, forceOverwrite bool) string {
-	for _, dependency := range manifest.Dependencies {
-		specNormalisedPath := fileutil.MakeAbsoluteRelativeToFile(dependency.Spec, manifestPath)
-		logrus.Debugf("bundling openapi spec: %v\n", specNormalisedPath)
+// stageSpecs writes the spec of each dependency, and its Imposter
+// configuration, to the staging directory. Server URL templates in each
+// spec are resolved using the lookup of the dependency.
+func stageSpecs(deps []DependencyContext, stagingDir string, forceOverwrite bool) error {
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].Name < deps[j].Name
+	})
 
-		specDestPath := filepath.Join(stagingDir, filepath.Base(specNormalisedPath))
-		err := fileutil.CopyContent(specNormalisedPath, specDestPath)
+	stagedFileNames := make(map[string]bool)
+	for _, dep := range deps {
+		logrus.Debugf("bundling spec for %v: %v\n", dep.Name, dep.SpecPath)
+		bundled, err := bundle(dep.SpecPath, dep.Lookup)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("failed to bundle spec for %v: %v", dep.Name, err)
+		}
+
+		specFileName := buildStagedSpecFileName(dep.Name, dep.SpecPath, bundled, stagedFileNames)
+		stagedFileNames[specFileName] = true
+		stagedFileNames[buildConfigFileName(specFileName)] = true
+		stagedFileNames[buildDependencyFileName(specFileName)] = true
+		specDestPath := filepath.Join(stagingDir, specFileName)
+		if err := ioutil.WriteFile(specDestPath, bundled, 0644); err != nil {
+			return err
 		}
 
-		WriteMockConfig(specDestPath, nil, forceOverwrite)
+		WriteMockConfig(specDestPath, dep.Name, nil, forceOverwrite)
 	}
-	return stagingDir
+	return nil
 }
 
-func WriteMockConfig(specFilePath string, resources []impostermodel.Resource, forceOverwrite bool) {
-	configFilePath := imposterfileutil.GenerateFilePathAdjacentToFile(specFilePath, "-config.yaml", forceOverwrite)
-	configFile, err := os.Create(configFilePath)
-	if err != nil {
-		panic(err)
+var invalidFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// buildStagedSpecFileName namespaces the spec file name with the dependency
+// name, so specs with the same file name, or one named the same as the bundled
+// manifest, do not overwrite each other in the staging directory.
+func buildStagedSpecFileName(depName string, specPath string, content []byte, taken map[string]bool) string {
+	baseName := specPath
+	if idx := strings.IndexAny(baseName, "?#"); idx >= 0 {
+		baseName = baseName[:idx]
+	}
+	baseName = filepath.Base(filepath.FromSlash(baseName))
+	ext := filepath.Ext(baseName)
+	if ext == "" {
+		if isJson(content) {
+			ext = ".json"
+		} else {
+			ext = ".yaml"
+		}
 	}
-	defer configFile.Close()
+	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
 
-	config := impostermodel.GenerateConfig(specFilePath, resources, impostermodel.ConfigGenerationOptions{
-		ScriptEngine: impostermodel.ScriptEngineNone,
-	})
+	prefix := "dep-" + invalidFileNameChars.ReplaceAllString(depName, "_") + "-" + invalidFileNameChars.ReplaceAllString(baseName, "_")
+	fileName := prefix + ext
+	for i := 2; taken[fileName] || taken[buildConfigFileName(fileName)] || taken[buildDependencyFileName(fileName)]; i++ {
+		fileName = fmt.Sprintf("%v-%d%v", prefix, i, ext)
+	}
+	return fileName
+}
+
+// mockConfigSuffix replaces the extension of a spec file
+// to name its Imposter configuration file.
+const mockConfigSuffix = "-config.yaml"
+
+// mockDependencySuffix replaces the extension of a spec file to name the
+// file recording the dependency it is mocked for. Imposter only reads
+// configuration files ending in '-config.yaml', '-config.yml' or
+// '-config.json', so it ignores this file.
+const mockDependencySuffix = "-dependency.yaml"
+
+// mockDependency records the OpenDeps dependency that a spec is mocked for.
+type mockDependency struct {
+	Dependency string `json:"dependency"`
+}
 
-	_, err = configFile.Write(config)
+// buildConfigFileName returns the name of the Imposter configuration file
+// that WriteMockConfig writes adjacent to the spec.
+func buildConfigFileName(specFileName string) string {
+	return replaceExtension(specFileName, mockConfigSuffix)
+}
+
+// buildDependencyFileName returns the name of the file recording the
+// dependency that WriteMockConfig writes adjacent to the spec.
+func buildDependencyFileName(specFileName string) string {
+	return replaceExtension(specFileName, mockDependencySuffix)
+}
+
+func replaceExtension(fileName string, suffix string) string {
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + suffix
+}
+
+// WriteMockConfig writes an Imposter configuration file for the spec, adjacent
+// to it. If dependencyName is not empty, it is recorded in a file named with
+// mockDependencySuffix, also adjacent to the spec.
+func WriteMockConfig(specFilePath string, dependencyName string, resources []impostermodel.Resource, forceOverwrite bool) {
+	configFilePath := imposterfileutil.GenerateFilePathAdjacentToFile(specFilePath, mockConfigSuffix, forceOverwrite)
+
+	config := impostermodel.PluginConfig{
+		Plugin:    "openapi",
+		SpecFile:  filepath.Base(specFilePath),
+		Resources: resources,
+	}
+	writeYaml(configFilePath, config)
+
+	if dependencyName != "" {
+		dependencyFilePath := filepath.Join(filepath.Dir(specFilePath), buildDependencyFileName(filepath.Base(specFilePath)))
+		writeYaml(dependencyFilePath, mockDependency{Dependency: dependencyName})
+	}
+}
+
+func writeYaml(filePath string, value interface{}) {
+	marshalled, err := yaml.Marshal(value)
 	if err != nil {
 		panic(err)
 	}
+	if err := ioutil.WriteFile(filePath, marshalled, 0644); err != nil {
+		panic(err)
+	}
 }
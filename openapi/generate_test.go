@@ -0,0 +1,90 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildStagedSpecFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		depName  string
+		specPath string
+		content  string
+		taken    map[string]bool
+		want     string
+	}{
+		{
+			name:     "namespaced by dependency",
+			depName:  "foo",
+			specPath: "/specs/openapi.yaml",
+			want:     "dep-foo-openapi.yaml",
+		},
+		{
+			name:     "remote spec without extension",
+			depName:  "foo service",
+			specPath: "https://example.com/spec?format=json",
+			content:  `{"openapi": "3.0.0"}`,
+			want:     "dep-foo_service-spec.json",
+		},
+		{
+			name:     "avoids taken config file name",
+			depName:  "foo",
+			specPath: "openapi.json",
+			taken:    map[string]bool{"dep-foo-openapi-config.yaml": true},
+			want:     "dep-foo-openapi-2.json",
+		},
+		{
+			name:     "avoids taken dependency file name",
+			depName:  "foo",
+			specPath: "openapi.yaml",
+			taken:    map[string]bool{"dep-foo-openapi-dependency.yaml": true},
+			want:     "dep-foo-openapi-2.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildStagedSpecFileName(tt.depName, tt.specPath, []byte(tt.content), tt.taken); got != tt.want {
+				t.Errorf("buildStagedSpecFileName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMockConfig(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "dep-foo-openapi.yaml")
+	WriteMockConfig(specPath, "foo", nil, true)
+
+	config, err := ioutil.ReadFile(filepath.Join(dir, "dep-foo-openapi-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "plugin: openapi\nspecFile: dep-foo-openapi.yaml\n"; string(config) != want {
+		t.Errorf("config = %q, want %q", config, want)
+	}
+	dependency, err := ioutil.ReadFile(filepath.Join(dir, "dep-foo-openapi-dependency.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "dependency: foo\n"; string(dependency) != want {
+		t.Errorf("dependency = %q, want %q", dependency, want)
+	}
+}
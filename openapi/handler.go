@@ -0,0 +1,261 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/manifest/model"
+	"strings"
+)
+
+// SpecHandler handles dependency specs of one kind, such as OpenAPI, so that
+// dependencies with specs of different kinds can coexist in a manifest.
+type SpecHandler interface {
+	// Kind is the name of the kind of spec, such as 'OpenAPI'.
+	Kind() string
+
+	// Detect determines whether the spec at the location is of this kind,
+	// from the location or the raw content, which is nil if it cannot be read.
+	Detect(location string, raw []byte) bool
+
+	// DiscoverSpecs returns the paths of the specs of this kind in dir
+	// that satisfy the options.
+	DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error)
+
+	// Scaffold returns a dependency holding the metadata of the spec at the
+	// location, whose content is raw, such as its summary, description,
+	// contact and version.
+	Scaffold(location string, raw []byte) (*model.Dependency, error)
+
+	// FindExternalRefs returns the references in the raw content of the spec,
+	// or a file it references, to other files, relative to its location.
+	FindExternalRefs(location string, raw []byte) ([]string, error)
+
+	// Check checks the availability of the dependency.
+	Check(dep DependencyContext) error
+
+	// Validate checks the dependency against its spec,
+	// returning the problems found.
+	Validate(dep DependencyContext) []Problem
+
+	// StartMock starts a mock of the dependencies, whose specs are all of
+	// this kind. If specs of this kind are not mocked, nil is returned.
+	StartMock(deps []DependencyContext, options MockOptions) (Mock, error)
+}
+
+// DependencyContext is a dependency of a manifest, with the location of
+// its spec and the server settings that apply to it.
+type DependencyContext struct {
+	ManifestPath string
+	Name         string
+	Dependency   model.Dependency
+
+	// SpecPath is the path or URL of the spec
+	SpecPath string
+
+	// ServerUrl overrides the server of the dependency, if set, such as
+	// from the command line or the selected environment
+	ServerUrl string
+
+	// EnvServer is the server of the dependency in the selected environment, if any
+	EnvServer *model.EnvironmentServer
+
+	// Lookup returns the values of server variables
+	Lookup VariableLookup
+}
+
+// ServerUrlOverride returns the server URL override or, failing that,
+// the availability URL of the dependency, if either is set.
+func (d DependencyContext) ServerUrlOverride() (string, bool) {
+	if d.ServerUrl != "" {
+		return d.ServerUrl, true
+	}
+	if d.Dependency.Availability != nil && d.Dependency.Availability.Url != "" {
+		return d.Dependency.Availability.Url, true
+	}
+	return "", false
+}
+
+// MockOptions configures the mocks started by handlers.
+type MockOptions struct {
+	ManifestPath string
+
+	// StagingDir is the directory in which specs mocked by Imposter are staged
+	StagingDir     string
+	ForceOverwrite bool
+
+	// Port is the port on which HTTP dependencies are mocked
+	Port int
+
+	// GrpcPort is the port on which gRPC dependencies are mocked
+	GrpcPort int
+}
+
+// Problem is a problem found by validating a dependency against its spec.
+type Problem struct {
+	// Pointer is the JSON pointer, relative to the dependency, of the
+	// field the problem concerns, such as '/x-channels/0', or empty if
+	// it concerns the spec
+	Pointer string
+
+	Message string
+}
+
+// Problemf returns a problem concerning the field at the pointer,
+// with a message formatted as for fmt.Sprintf.
+func Problemf(pointer string, format string, args ...interface{}) Problem {
+	return Problem{Pointer: pointer, Message: fmt.Sprintf(format, args...)}
+}
+
+// Mock is a running mock of dependencies.
+type Mock interface {
+	Stop()
+
+	// Done returns a channel that is closed when the mock stops,
+	// whether it is stopped or exits by itself.
+	Done() <-chan struct{}
+}
+
+// Handler handles OpenAPI 3 and Swagger 2.0 specs.
+var Handler SpecHandler = openApiHandler{}
+
+type openApiHandler struct{}
+
+func (openApiHandler) Kind() string {
+	return "OpenAPI"
+}
+
+func (openApiHandler) Detect(_ string, raw []byte) bool {
+	return IsOpenApiSpec(raw)
+}
+
+func (openApiHandler) DiscoverSpecs(dir string, options fileutil.FindOptions) ([]string, error) {
+	return DiscoverSpecs(dir, options)
+}
+
+func (openApiHandler) Scaffold(_ string, raw []byte) (*model.Dependency, error) {
+	spec, err := ParseContent(raw)
+	if err != nil {
+		return nil, err
+	}
+	dep := &model.Dependency{
+		Summary:     spec.Info.Title,
+		Description: strings.TrimSpace(spec.Info.Description),
+		Version:     spec.Info.Version,
+	}
+	if contact := spec.Info.Contact; contact != nil && (contact.Name != "" || contact.Email != "" || contact.Url != "") {
+		dep.Contact = &model.Contact{Name: contact.Name, Email: contact.Email, Url: contact.Url}
+	}
+	return dep, nil
+}
+
+// resolveSpecServerUrl returns the URL of the server in the spec selected by
+// the environment server, if not nil, otherwise that of the first server.
+func resolveSpecServerUrl(location string, envServer *model.EnvironmentServer, lookup VariableLookup) (string, error) {
+	spec, err := Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse spec [%v]: %v\n", location, err)
+	}
+	if len(spec.Servers) == 0 {
+		return "", fmt.Errorf("no servers found in spec [%v]\n", location)
+	}
+
+	var server *Server
+	if envServer != nil && "" != envServer.Description {
+		server, err = FindServerByDescription(spec.Servers, envServer.Description)
+	} else if envServer != nil && len(envServer.Variables) > 0 {
+		server, err = FindServerByVariables(spec.Servers, envServer.Variables)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to select server in spec [%v]: %v", location, err)
+	} else if server != nil {
+		logrus.Debugf("selected server [%v] in spec [%v]", server.Url, location)
+	} else {
+		if len(spec.Servers) > 1 {
+			logrus.Warnf("more than 1 server found in spec [%v] - using first\n", location)
+		}
+		server = &spec.Servers[0]
+	}
+	return ResolveServerUrl(*server, lookup)
+}
+
+func (openApiHandler) FindExternalRefs(_ string, raw []byte) ([]string, error) {
+	return FindExternalRefs(raw)
+}
+
+// bundle returns the content of the spec to stage for mocks, with its
+// external references inlined and its server URLs resolved.
+func bundle(location string, lookup VariableLookup) ([]byte, error) {
+	bundled, err := InlineExternalRefs(location)
+	if err != nil {
+		return nil, err
+	}
+	if IsSwagger2(bundled) {
+		logrus.Debugf("converting Swagger 2.0 spec to OpenAPI 3: %v", location)
+		bundled, err = ConvertSwagger2(bundled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert Swagger spec: %v", err)
+		}
+	}
+	bundled, err = SubstituteServerVariables(bundled, lookup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to substitute server variables: %v", err)
+	}
+	return bundled, nil
+}
+
+// Validate checks that the spec is an OpenAPI 3 or Swagger 2.0 spec that is
+// valid against the schema for its version, that it defines the availability
+// path as a GET operation, and that its version satisfies the version
+// constraint of the dependency.
+func (openApiHandler) Validate(dep DependencyContext) []Problem {
+	raw, err := fileutil.ReadAllContent(dep.SpecPath)
+	if err != nil {
+		return []Problem{Problemf("", "spec could not be read: %v", err)}
+	}
+	spec, err := ParseContent(raw)
+	switch {
+	case err != nil:
+		return []Problem{Problemf("", "spec could not be read: %v", err)}
+	case spec.IsSwagger2():
+		logrus.Infof("dependency [%v] spec is Swagger %v - it will be converted to OpenAPI 3 for mocks", dep.Name, spec.Swagger)
+		if availability := dep.Dependency.Availability; spec.Host == "" && dep.ServerUrl == "" && availability != nil && availability.Url == "" && availability.Path != "" {
+			logrus.Warnf("dependency [%v] spec is Swagger with no host - its availability path can only be tested with an environment server URL or a --server override", dep.Name)
+		}
+	case spec.OpenApi != "":
+		logrus.Debugf("dependency [%v] spec is OpenAPI %v", dep.Name, spec.OpenApi)
+	default:
+		return []Problem{Problemf("", "spec is not an OpenAPI or Swagger spec: %v", dep.SpecPath)}
+	}
+
+	var problems []Problem
+	schemaErrors, err := ValidateSchema(raw)
+	if err != nil {
+		problems = append(problems, Problemf("", "spec could not be validated: %v", err))
+	}
+	for _, schemaErr := range schemaErrors {
+		problems = append(problems, Problemf("", "spec is invalid at %v", schemaErr))
+	}
+	if availability := dep.Dependency.Availability; availability != nil && availability.Url == "" && availability.Path != "" {
+		if !spec.HasGetOperation(availability.Path) {
+			problems = append(problems, Problemf("/availability/path", "availability path [%v] is not a GET operation in its spec", availability.Path))
+		}
+	}
+	return append(problems, ValidateVersion(dep, spec.Info.Version, determineBasePath)...)
+}
@@ -0,0 +1,398 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"net/url"
+	"opendeps.org/opendeps/fileutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// refInliner replaces references to other documents with the content
+// they refer to, so a spec split across files becomes a single document.
+type refInliner struct {
+	rootLocation string
+	docs         map[string]interface{}
+
+	// stack holds the references currently being resolved, to detect cycles
+	stack []string
+
+	// hoisted holds the component names of references that are
+	// cyclic, and so must be placed in the root document's schemas
+	hoisted        map[string]string
+	hoistedOrder   []string
+	hoistedContent map[string]interface{}
+}
+
+// InlineExternalRefs loads the spec at specLocation and replaces each `$ref`
+// to another document with the referenced content. References within the
+// spec itself are unchanged. Cyclic references are moved into the spec's
+// `components/schemas` and referenced from there.
+//
+// If the spec has no external references, its raw content is returned unchanged.
+func InlineExternalRefs(specLocation string) ([]byte, error) {
+	raw, err := fileutil.ReadAllContent(specLocation)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := FindExternalRefs(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec [%v]: %v", specLocation, err)
+	} else if len(refs) == 0 {
+		return raw, nil
+	}
+	logrus.Debugf("inlining %d external reference(s) in spec: %v", len(refs), specLocation)
+
+	root, err := parseOrdered(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec [%v]: %v", specLocation, err)
+	}
+	inliner := &refInliner{
+		rootLocation:   specLocation,
+		docs:           map[string]interface{}{specLocation: root},
+		hoisted:        make(map[string]string),
+		hoistedContent: make(map[string]interface{}),
+	}
+	inlined, err := inliner.resolveNode(root, specLocation)
+	if err != nil {
+		return nil, err
+	}
+	inlined = inliner.addHoisted(inlined)
+
+	if isJson(raw) {
+		var buf bytes.Buffer
+		if err := writeOrderedJson(&buf, inlined); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return yaml.Marshal(inlined)
+}
+
+func parseOrdered(raw []byte) (interface{}, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (r *refInliner) resolveNode(node interface{}, location string) (interface{}, error) {
+	switch n := node.(type) {
+	case yaml.MapSlice:
+		for _, item := range n {
+			if item.Key == "$ref" {
+				if ref, ok := item.Value.(string); ok {
+					return r.resolveRef(n, ref, location)
+				}
+			}
+		}
+		resolved := make(yaml.MapSlice, 0, len(n))
+		for _, item := range n {
+			value, err := r.resolveNode(item.Value, location)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, yaml.MapItem{Key: item.Key, Value: value})
+		}
+		return resolved, nil
+
+	case []interface{}:
+		resolved := make([]interface{}, 0, len(n))
+		for _, v := range n {
+			value, err := r.resolveNode(v, location)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, value)
+		}
+		return resolved, nil
+
+	default:
+		return node, nil
+	}
+}
+
+func (r *refInliner) resolveRef(node yaml.MapSlice, ref string, location string) (interface{}, error) {
+	docRef, fragment := SplitRef(ref)
+	targetLocation := location
+	if docRef != "" {
+		var err error
+		targetLocation, err = fileutil.ResolveLocation(location, docRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if targetLocation == r.rootLocation {
+		// refers to the root document, which is retained
+		return replaceRef(node, "#"+fragment), nil
+	}
+
+	key := targetLocation + "#" + fragment
+	if name, found := r.hoisted[key]; found {
+		if _, resolved := r.hoistedContent[key]; resolved {
+			return replaceRef(node, "#/components/schemas/"+name), nil
+		}
+	}
+	for _, pending := range r.stack {
+		if pending == key {
+			return replaceRef(node, "#/components/schemas/"+r.hoist(key, fragment, targetLocation)), nil
+		}
+	}
+
+	doc, err := r.loadDoc(targetLocation)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolvePointer(doc, fragment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reference [%v] in [%v]: %v", ref, location, err)
+	}
+
+	r.stack = append(r.stack, key)
+	resolved, err := r.resolveNode(target, targetLocation)
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	if name, found := r.hoisted[key]; found {
+		r.hoistedContent[key] = resolved
+		return replaceRef(node, "#/components/schemas/"+name), nil
+	}
+	return mergeSiblings(node, resolved), nil
+}
+
+func (r *refInliner) loadDoc(location string) (interface{}, error) {
+	if doc, found := r.docs[location]; found {
+		return doc, nil
+	}
+	logrus.Tracef("loading referenced document: %v", location)
+	raw, err := fileutil.ReadAllContent(location)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseOrdered(raw)
+	if err != nil {
+		// not a mapping, such as a document containing only an array
+		var generic interface{}
+		if err := yaml.Unmarshal(raw, &generic); err != nil {
+			return nil, fmt.Errorf("failed to parse referenced document [%v]: %v", location, err)
+		}
+		doc = generic
+	}
+	r.docs[location] = doc
+	return doc, nil
+}
+
+var invalidComponentChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// hoist allocates a unique component name for a cyclic reference.
+func (r *refInliner) hoist(key string, fragment string, location string) string {
+	if name, found := r.hoisted[key]; found {
+		return name
+	}
+	base := path.Base(fragment)
+	if fragment == "" || base == "/" || base == "." {
+		base = strings.TrimSuffix(path.Base(location), path.Ext(location))
+	}
+	base = invalidComponentChars.ReplaceAllString(base, "_")
+
+	name := base
+	for i := 2; r.isNameTaken(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	r.hoisted[key] = name
+	r.hoistedOrder = append(r.hoistedOrder, key)
+	return name
+}
+
+func (r *refInliner) isNameTaken(name string) bool {
+	root, _ := r.docs[r.rootLocation].(yaml.MapSlice)
+	components, _ := getItem(root, "components").(yaml.MapSlice)
+	schemas, _ := getItem(components, "schemas").(yaml.MapSlice)
+	if getItem(schemas, name) != nil {
+		return true
+	}
+	// Swagger 2.0 definitions become schemas when the spec is converted
+	definitions, _ := getItem(root, "definitions").(yaml.MapSlice)
+	if getItem(definitions, name) != nil {
+		return true
+	}
+	for _, taken := range r.hoisted {
+		if taken == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addHoisted places the content of cyclic references in the
+// root document's `components/schemas`.
+func (r *refInliner) addHoisted(root interface{}) interface{} {
+	if len(r.hoistedOrder) == 0 {
+		return root
+	}
+	doc, _ := root.(yaml.MapSlice)
+	components, _ := getItem(doc, "components").(yaml.MapSlice)
+	schemas, _ := getItem(components, "schemas").(yaml.MapSlice)
+	for _, key := range r.hoistedOrder {
+		schemas = append(schemas, yaml.MapItem{Key: r.hoisted[key], Value: r.hoistedContent[key]})
+	}
+	components = setItem(components, "schemas", schemas)
+	return setItem(doc, "components", components)
+}
+
+func getItem(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func setItem(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range m {
+		if item.Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+// replaceRef returns a copy of node with its `$ref` replaced.
+func replaceRef(node yaml.MapSlice, ref string) yaml.MapSlice {
+	replaced := make(yaml.MapSlice, 0, len(node))
+	for _, item := range node {
+		if item.Key == "$ref" {
+			item.Value = ref
+		}
+		replaced = append(replaced, item)
+	}
+	return replaced
+}
+
+// mergeSiblings applies any keys alongside a `$ref`, such as
+// `description`, to the content that replaces it.
+func mergeSiblings(node yaml.MapSlice, resolved interface{}) interface{} {
+	if len(node) == 1 {
+		return resolved
+	}
+	resolvedMap, ok := resolved.(yaml.MapSlice)
+	if !ok {
+		return resolved
+	}
+	merged := append(yaml.MapSlice{}, resolvedMap...)
+	for _, item := range node {
+		if item.Key != "$ref" {
+			merged = setItem(merged, fmt.Sprint(item.Key), item.Value)
+		}
+	}
+	return merged
+}
+
+// resolvePointer navigates doc using a JSON pointer, such as `/components/schemas/User`.
+func resolvePointer(doc interface{}, pointer string) (interface{}, error) {
+	if pointer == "" || pointer == "/" {
+		return doc, nil
+	}
+	unescaped, err := url.PathUnescape(pointer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pointer: %v", pointer)
+	}
+
+	current := doc
+	for _, token := range strings.Split(strings.TrimPrefix(unescaped, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch n := current.(type) {
+		case yaml.MapSlice:
+			found := false
+			for _, item := range n {
+				if fmt.Sprint(item.Key) == token {
+					current = item.Value
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("no such element: %v", token)
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return nil, fmt.Errorf("invalid array index: %v", token)
+			}
+			current = n[idx]
+		default:
+			return nil, fmt.Errorf("cannot navigate into: %v", token)
+		}
+	}
+	return current, nil
+}
+
+func isJson(raw []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(raw)), "{")
+}
+
+// writeOrderedJson serialises a document parsed with parseOrdered as JSON,
+// retaining its key order.
+func writeOrderedJson(buf *bytes.Buffer, node interface{}) error {
+	switch n := node.(type) {
+	case yaml.MapSlice:
+		buf.WriteString("{")
+		for i, item := range n {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(fmt.Sprint(item.Key))
+			buf.Write(key)
+			buf.WriteString(":")
+			if err := writeOrderedJson(buf, item.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+	case []interface{}:
+		buf.WriteString("[")
+		for i, v := range n {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeOrderedJson(buf, v); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+	default:
+		value, err := json.Marshal(n)
+		if err != nil {
+			return fmt.Errorf("failed to serialise value [%v]: %v", n, err)
+		}
+		buf.Write(value)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"gatehill.io/imposter/engine"
+	"gatehill.io/imposter/engine/docker"
+	"sync"
+)
+
+// imposterMock is an Imposter mock engine, serving the staged specs.
+type imposterMock struct {
+	engine engine.MockEngine
+	wg     *sync.WaitGroup
+	done   chan struct{}
+}
+
+// StartMock stages the specs of the dependencies, with their Imposter
+// configuration, then starts Imposter to serve the staging directory.
+func (openApiHandler) StartMock(deps []DependencyContext, options MockOptions) (Mock, error) {
+	if err := stageSpecs(deps, options.StagingDir, options.ForceOverwrite); err != nil {
+		return nil, err
+	}
+
+	engineType := docker.EnableEngine()
+	mockEngine := engine.BuildEngine(engineType, options.StagingDir, engine.StartOptions{
+		Port:           options.Port,
+		Version:        "latest",
+		PullPolicy:     engine.PullIfNotPresent,
+		LogLevel:       "DEBUG",
+		ReplaceRunning: true,
+		Deduplicate:    genDeduplicationKey(options.ManifestPath, options.Port),
+	})
+	wg := &sync.WaitGroup{}
+	if !mockEngine.Start(wg) {
+		return nil, fmt.Errorf("failed to start mock engine on port %d", options.Port)
+	}
+	mock := &imposterMock{engine: mockEngine, wg: wg, done: make(chan struct{})}
+	go func() {
+		// the engine marks the wait group done when its container exits
+		wg.Wait()
+		close(mock.done)
+	}()
+	return mock, nil
+}
+
+// genDeduplicationKey overrides the default deduplication key to a
+// stable value, since the staging dir is dynamic
+func genDeduplicationKey(manifestPath string, port int) string {
+	return fmt.Sprintf("%v:%d", manifestPath, port)
+}
+
+func (m *imposterMock) Stop() {
+	select {
+	case <-m.done:
+		// the container has already exited
+	default:
+		m.engine.StopImmediately(m.wg)
+		<-m.done
+	}
+}
+
+func (m *imposterMock) Done() <-chan struct{} {
+	return m.done
+}
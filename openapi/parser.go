@@ -22,22 +22,65 @@ import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"opendeps.org/opendeps/fileutil"
+	"strings"
 )
 
+type Contact struct {
+	Name  string
+	Url   string
+	Email string
+}
+
 type Info struct {
-	Title   string
-	Version string
+	Title       string
+	Description string
+	Version     string
+	Contact     *Contact
+}
+
+type ServerVariable struct {
+	Default     string
+	Enum        []string
+	Description string
 }
 
 type Server struct {
 	Url         string
 	Description string
+	Variables   map[string]ServerVariable
+}
+
+// SecurityScheme is an entry in components.securitySchemes.
+type SecurityScheme struct {
+	Type   string
+	Scheme string
+	In     string
+	Name   string
+}
+
+type Components struct {
+	SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes"`
 }
 
 type PartialModel struct {
-	Info    Info
-	Paths   map[string]map[string]interface{}
-	Servers []Server
+	OpenApi    string `yaml:"openapi"`
+	Info       Info
+	Paths      map[string]map[string]interface{}
+	Servers    []Server
+	Security   []map[string][]string
+	Components Components
+
+	// Swagger 2.0 fields, from which Servers and Components are derived
+	Swagger             string
+	Host                string
+	BasePath            string `yaml:"basePath"`
+	Schemes             []string
+	SecurityDefinitions map[string]SecurityScheme `yaml:"securityDefinitions"`
+}
+
+// IsSwagger2 determines whether the spec is a Swagger 2.0 spec.
+func (o *PartialModel) IsSwagger2() bool {
+	return o.Swagger != ""
 }
 
 func Parse(specFile string) (*PartialModel, error) {
@@ -51,13 +94,72 @@ func Parse(specFile string) (*PartialModel, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ParseContent(raw)
+}
 
+// ParseContent parses the raw content of an OpenAPI spec.
+func ParseContent(raw []byte) (*PartialModel, error) {
 	o := PartialModel{}
-	err = yaml.Unmarshal(raw, &o)
+	err := yaml.Unmarshal(raw, &o)
 	if err != nil {
 		return nil, fmt.Errorf("error: %v\n", err)
 	}
 
+	if o.IsSwagger2() {
+		normaliseSwagger2(&o)
+	}
+
 	logrus.Tracef("openapi parsed:\n%v\n\n", o)
 	return &o, nil
 }
+
+// normaliseSwagger2 populates the OpenAPI 3 servers and security schemes
+// of a Swagger 2.0 spec, so it can be treated like any other spec.
+func normaliseSwagger2(o *PartialModel) {
+	if len(o.Servers) == 0 {
+		for _, url := range BuildSwaggerServerUrls(o.Schemes, o.Host, o.BasePath) {
+			o.Servers = append(o.Servers, Server{Url: url})
+		}
+	}
+	if o.Components.SecuritySchemes == nil && len(o.SecurityDefinitions) > 0 {
+		o.Components.SecuritySchemes = make(map[string]SecurityScheme)
+		for name, definition := range o.SecurityDefinitions {
+			if definition.Type == "basic" {
+				definition = SecurityScheme{Type: "http", Scheme: "basic"}
+			}
+			o.Components.SecuritySchemes[name] = definition
+		}
+	}
+}
+
+// HasGetOperation determines whether the spec defines a GET operation for the
+// path, ignoring any query string. Templated paths, such as '/pets/{petId}',
+// match any value of their parameters.
+func (o *PartialModel) HasGetOperation(path string) bool {
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	for specPath, operations := range o.Paths {
+		if _, found := operations["get"]; found && pathMatches(specPath, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathMatches(template string, path string) bool {
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strings"
+)
+
+// FindExternalRefs returns the distinct documents referenced by `$ref`s in
+// the raw spec, excluding references within the same document.
+// Fragments are removed from the returned references.
+func FindExternalRefs(raw []byte) ([]string, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("error: %v\n", err)
+	}
+
+	found := make(map[string]bool)
+	walkRefs(doc, func(ref string) {
+		if docRef, _ := SplitRef(ref); docRef != "" {
+			found[docRef] = true
+		}
+	})
+
+	var refs []string
+	for ref := range found {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs, nil
+}
+
+// SplitRef separates a `$ref` into the referenced document and the
+// JSON pointer fragment within it. The document is empty for
+// references within the same document.
+func SplitRef(ref string) (docRef string, fragment string) {
+	if idx := strings.Index(ref, "#"); idx >= 0 {
+		return ref[:idx], ref[idx+1:]
+	}
+	return ref, ""
+}
+
+// walkRefs invokes fn for the value of every `$ref` in the node tree.
+func walkRefs(node interface{}, fn func(ref string)) {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range n {
+			if k == "$ref" {
+				if ref, ok := v.(string); ok {
+					fn(ref)
+					continue
+				}
+			}
+			walkRefs(v, fn)
+		}
+	case []interface{}:
+		for _, v := range n {
+			walkRefs(v, fn)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"io"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+// schemaFiles holds the JSON schemas of OpenAPI 3.0 and 3.1, and Swagger 2.0,
+// and the draft-04 meta-schema that the Swagger schema refers to, so specs
+// can be validated offline.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// The specs are validated with santhosh-tekuri/jsonschema, rather than the
+// gojsonschema library used for manifests, because gojsonschema only supports
+// JSON schema drafts 4, 6 and 7, while the OpenAPI 3.1 schema is written in
+// draft 2020-12 and relies on $dynamicRef and $dynamicAnchor.
+
+// specSchemaUrls maps OpenAPI and Swagger versions to the URLs of their schemas.
+var specSchemaUrls = map[string]string{
+	"2.0": "http://swagger.io/v2/schema.json",
+	"3.0": "https://spec.openapis.org/oas/3.0/schema/2021-09-28",
+	"3.1": "https://spec.openapis.org/oas/3.1/schema/2022-10-07",
+}
+
+// schemaFileNames maps schema URLs to the embedded files holding them.
+var schemaFileNames = map[string]string{
+	"http://json-schema.org/draft-04/schema":              "schemas/draft-04.json",
+	"http://swagger.io/v2/schema.json":                    "schemas/swagger-2.0.json",
+	"https://spec.openapis.org/oas/3.0/schema/2021-09-28": "schemas/openapi-3.0.json",
+	"https://spec.openapis.org/oas/3.1/schema/2022-10-07": "schemas/openapi-3.1.json",
+}
+
+// compiledSchemas caches the compiled schemas by version
+var compiledSchemas = make(map[string]*jsonschema.Schema)
+
+var specVersionPattern = regexp.MustCompile(`^(\d+\.\d+)`)
+
+// SchemaError is a violation of the schema of a spec.
+type SchemaError struct {
+	// Pointer is the JSON pointer to the invalid value
+	Pointer string
+
+	Message string
+}
+
+func (e SchemaError) String() string {
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%v: %v", pointer, e.Message)
+}
+
+// ValidateSchema validates the raw spec against the embedded JSON schema for
+// its version of OpenAPI or Swagger, returning the violations, if any.
+func ValidateSchema(raw []byte) ([]SchemaError, error) {
+	spec, err := ParseContent(raw)
+	if err != nil {
+		return nil, err
+	}
+	version := spec.OpenApi
+	if spec.IsSwagger2() {
+		version = spec.Swagger
+	}
+	schema, err := loadSpecSchema(version)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if err := schema.Validate(doc); err == nil {
+		return nil, nil
+	} else if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+	return collectSchemaErrors(validationErr), nil
+}
+
+// loadSpecSchema returns the compiled schema for the version of OpenAPI or Swagger.
+func loadSpecSchema(version string) (*jsonschema.Schema, error) {
+	majorMinor := specVersionPattern.FindString(version)
+	schemaUrl, found := specSchemaUrls[majorMinor]
+	if !found {
+		return nil, fmt.Errorf("no schema for OpenAPI version [%v]", version)
+	}
+	if schema, found := compiledSchemas[majorMinor]; found {
+		return schema, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = loadEmbeddedSchema
+	schema, err := compiler.Compile(schemaUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema for OpenAPI version [%v]: %v", majorMinor, err)
+	}
+	compiledSchemas[majorMinor] = schema
+	return schema, nil
+}
+
+// loadEmbeddedSchema opens the embedded file holding the schema at the URL.
+// Schemas that are not embedded are never fetched.
+func loadEmbeddedSchema(schemaUrl string) (io.ReadCloser, error) {
+	fileName, found := schemaFileNames[strings.TrimSuffix(schemaUrl, "#")]
+	if !found {
+		return nil, fmt.Errorf("schema [%v] is not available offline", schemaUrl)
+	}
+	return schemaFiles.Open(fileName)
+}
+
+// collectSchemaErrors returns the most specific errors, those without
+// causes, in order of their location in the spec.
+func collectSchemaErrors(validationErr *jsonschema.ValidationError) []SchemaError {
+	var schemaErrors []SchemaError
+	seen := make(map[SchemaError]bool)
+	var collect func(*jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				collect(cause)
+			}
+			return
+		}
+		schemaErr := SchemaError{Pointer: e.InstanceLocation, Message: e.Message}
+		if !seen[schemaErr] {
+			seen[schemaErr] = true
+			schemaErrors = append(schemaErrors, schemaErr)
+		}
+	}
+	collect(validationErr)
+	sort.SliceStable(schemaErrors, func(i, j int) bool {
+		return strings.Compare(schemaErrors[i].Pointer, schemaErrors[j].Pointer) < 0
+	})
+	return schemaErrors
+}
@@ -0,0 +1,165 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// VariableLookup returns the value to use for a server variable,
+// if one has been provided.
+type VariableLookup func(name string) (value string, found bool)
+
+var serverVariablePattern = regexp.MustCompile(`\{([^}]+)}`)
+
+// FindServerByDescription returns the first server whose description
+// matches, ignoring case.
+func FindServerByDescription(servers []Server, description string) (*Server, error) {
+	for _, server := range servers {
+		if strings.EqualFold(strings.TrimSpace(server.Description), strings.TrimSpace(description)) {
+			return &server, nil
+		}
+	}
+	return nil, fmt.Errorf("no server found with description [%v]\n", description)
+}
+
+// FindServerByVariables returns the first server that declares all of the
+// given variables, and whose enums, if any, permit their values.
+func FindServerByVariables(servers []Server, variables map[string]string) (*Server, error) {
+	for _, server := range servers {
+		matches := true
+		for name, value := range variables {
+			variable, found := server.Variables[name]
+			if !found || !isPermitted(variable, value) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return &server, nil
+		}
+	}
+
+	var names []string
+	for name, value := range variables {
+		names = append(names, name+"="+value)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("no server found accepting variables [%v]\n", strings.Join(names, ", "))
+}
+
+// ResolveServerUrl substitutes the variables in the server's URL template.
+// Values are taken from lookup if provided, otherwise the variable's default.
+func ResolveServerUrl(server Server, lookup VariableLookup) (string, error) {
+	var errs []string
+	resolved := serverVariablePattern.ReplaceAllStringFunc(server.Url, func(placeholder string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(placeholder, "{"), "}")
+		variable, declared := server.Variables[name]
+
+		value, found := "", false
+		if lookup != nil {
+			value, found = lookup(name)
+		}
+		if !found {
+			if !declared {
+				errs = append(errs, fmt.Sprintf("variable [%v] is not declared and has no value", name))
+				return placeholder
+			}
+			value = variable.Default
+		} else if declared && !isPermitted(variable, value) {
+			errs = append(errs, fmt.Sprintf("value [%v] for variable [%v] is not one of: %v", value, name, strings.Join(variable.Enum, ", ")))
+		}
+		return value
+	})
+	if len(errs) > 0 {
+		return "", fmt.Errorf("failed to resolve server URL [%v]: %v\n", server.Url, strings.Join(errs, "; "))
+	}
+	return resolved, nil
+}
+
+func isPermitted(variable ServerVariable, value string) bool {
+	if len(variable.Enum) == 0 {
+		return true
+	}
+	for _, permitted := range variable.Enum {
+		if permitted == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SubstituteServerVariables resolves the URL templates of the top level
+// servers in the raw spec, removing their variables. If no server
+// declares variables, the raw content is returned unchanged.
+func SubstituteServerVariables(raw []byte, lookup VariableLookup) ([]byte, error) {
+	spec, err := ParseContent(raw)
+	if err != nil {
+		return nil, err
+	}
+	templated := false
+	for _, server := range spec.Servers {
+		if serverVariablePattern.MatchString(server.Url) {
+			templated = true
+			break
+		}
+	}
+	if !templated {
+		return raw, nil
+	}
+
+	doc, err := parseOrdered(raw)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.(yaml.MapSlice)
+	servers, _ := getItem(root, "servers").([]interface{})
+	for i, s := range servers {
+		serverNode, ok := s.(yaml.MapSlice)
+		if !ok || i >= len(spec.Servers) {
+			continue
+		}
+		resolved, err := ResolveServerUrl(spec.Servers[i], lookup)
+		if err != nil {
+			return nil, err
+		}
+		var substituted yaml.MapSlice
+		for _, item := range serverNode {
+			if item.Key == "variables" {
+				continue
+			} else if item.Key == "url" {
+				item.Value = resolved
+			}
+			substituted = append(substituted, item)
+		}
+		servers[i] = substituted
+	}
+
+	if isJson(raw) {
+		var buf bytes.Buffer
+		if err := writeOrderedJson(&buf, root); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return yaml.Marshal(root)
+}
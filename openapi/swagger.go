@@ -0,0 +1,585 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"strings"
+)
+
+const convertedOpenApiVersion = "3.0.3"
+
+// parameterSchemaKeys are the keys of a Swagger 2.0 parameter or header
+// that describe its value, and so move into its schema in OpenAPI 3
+var parameterSchemaKeys = map[string]bool{
+	"type": true, "format": true, "items": true, "default": true, "enum": true,
+	"maximum": true, "exclusiveMaximum": true, "minimum": true, "exclusiveMinimum": true,
+	"maxLength": true, "minLength": true, "pattern": true,
+	"maxItems": true, "minItems": true, "uniqueItems": true, "multipleOf": true,
+}
+
+var operationKeys = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// oauth2Flows maps the Swagger 2.0 OAuth2 flow names to those of OpenAPI 3
+var oauth2Flows = map[string]string{
+	"implicit":    "implicit",
+	"password":    "password",
+	"application": "clientCredentials",
+	"accessCode":  "authorizationCode",
+}
+
+// IsSwagger2 determines whether the raw content is a Swagger 2.0 spec.
+func IsSwagger2(raw []byte) bool {
+	var doc struct {
+		Swagger string
+	}
+	return yaml.Unmarshal(raw, &doc) == nil && doc.Swagger != ""
+}
+
+// BuildSwaggerServerUrls derives server URLs from the schemes, host and base
+// path of a Swagger 2.0 spec. If there is no host, the URL is the base path.
+func BuildSwaggerServerUrls(schemes []string, host string, basePath string) []string {
+	if basePath == "" {
+		basePath = "/"
+	}
+	if host == "" {
+		return []string{basePath}
+	}
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	var urls []string
+	for _, scheme := range schemes {
+		urls = append(urls, scheme+"://"+host+strings.TrimSuffix(basePath, "/"))
+	}
+	return urls
+}
+
+// ConvertSwagger2 converts a Swagger 2.0 spec to OpenAPI 3, retaining the
+// order of its keys and its format (YAML or JSON). References within the
+// spec are rewritten; external references should be inlined beforehand.
+func ConvertSwagger2(raw []byte) ([]byte, error) {
+	root, err := parseOrdered(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Swagger spec: %v", err)
+	}
+	converted := rewriteSwaggerRefs(newSwaggerConverter(root.(yaml.MapSlice)).convert())
+
+	if isJson(raw) {
+		var buf bytes.Buffer
+		if err := writeOrderedJson(&buf, converted); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return yaml.Marshal(converted)
+}
+
+type swaggerConverter struct {
+	doc       yaml.MapSlice
+	consumes  []string
+	produces  []string
+	globalRef map[string]yaml.MapSlice
+}
+
+func newSwaggerConverter(doc yaml.MapSlice) *swaggerConverter {
+	c := &swaggerConverter{
+		doc:       doc,
+		consumes:  toStrings(getItem(doc, "consumes")),
+		produces:  toStrings(getItem(doc, "produces")),
+		globalRef: make(map[string]yaml.MapSlice),
+	}
+	if len(c.consumes) == 0 {
+		c.consumes = []string{"application/json"}
+	}
+	if len(c.produces) == 0 {
+		c.produces = []string{"application/json"}
+	}
+	parameters, _ := getItem(doc, "parameters").(yaml.MapSlice)
+	for _, item := range parameters {
+		if param, ok := item.Value.(yaml.MapSlice); ok {
+			c.globalRef["#/parameters/"+fmt.Sprint(item.Key)] = param
+		}
+	}
+	return c
+}
+
+func (c *swaggerConverter) convert() yaml.MapSlice {
+	out := yaml.MapSlice{{Key: "openapi", Value: convertedOpenApiVersion}}
+	var components yaml.MapSlice
+
+	for _, item := range c.doc {
+		switch item.Key {
+		case "swagger", "host", "basePath", "schemes", "consumes", "produces":
+			continue
+		case "info":
+			out = append(out, item)
+			out = append(out, yaml.MapItem{Key: "servers", Value: c.convertServers()})
+		case "paths":
+			out = append(out, yaml.MapItem{Key: "paths", Value: c.convertPaths(item.Value)})
+		case "definitions":
+			components = mergeComponents(components, "schemas", convertSchema(item.Value))
+		case "parameters":
+			if params := c.convertGlobalParameters(item.Value); len(params) > 0 {
+				components = setItem(components, "parameters", params)
+			}
+		case "responses":
+			responses := yaml.MapSlice{}
+			if m, ok := item.Value.(yaml.MapSlice); ok {
+				for _, response := range m {
+					responses = append(responses, yaml.MapItem{Key: response.Key, Value: c.convertResponse(response.Value, c.produces)})
+				}
+			}
+			components = setItem(components, "responses", responses)
+		case "securityDefinitions":
+			components = setItem(components, "securitySchemes", convertSecurityDefinitions(item.Value))
+		case "components":
+			// schemas hoisted while inlining external references
+			existing, _ := item.Value.(yaml.MapSlice)
+			for _, component := range existing {
+				components = mergeComponents(components, fmt.Sprint(component.Key), component.Value)
+			}
+		default:
+			out = append(out, item)
+		}
+	}
+	if getItem(out, "servers") == nil {
+		out = append(out, yaml.MapItem{Key: "servers", Value: c.convertServers()})
+	}
+	if len(components) > 0 {
+		out = append(out, yaml.MapItem{Key: "components", Value: components})
+	}
+	return out
+}
+
+func (c *swaggerConverter) convertServers() []interface{} {
+	host, _ := getItem(c.doc, "host").(string)
+	basePath, _ := getItem(c.doc, "basePath").(string)
+	var servers []interface{}
+	for _, url := range BuildSwaggerServerUrls(toStrings(getItem(c.doc, "schemes")), host, basePath) {
+		servers = append(servers, yaml.MapSlice{{Key: "url", Value: url}})
+	}
+	return servers
+}
+
+func (c *swaggerConverter) convertPaths(node interface{}) yaml.MapSlice {
+	paths, _ := node.(yaml.MapSlice)
+	converted := make(yaml.MapSlice, 0, len(paths))
+	for _, pathEntry := range paths {
+		pathItem, ok := pathEntry.Value.(yaml.MapSlice)
+		if !ok {
+			converted = append(converted, pathEntry)
+			continue
+		}
+
+		// body and form parameters at path level apply to each operation
+		pathParams, _ := getItem(pathItem, "parameters").([]interface{})
+		var sharedParams, bodyParams []interface{}
+		for _, p := range pathParams {
+			if c.isBodyOrForm(p) {
+				bodyParams = append(bodyParams, p)
+			} else {
+				sharedParams = append(sharedParams, p)
+			}
+		}
+
+		convertedItem := make(yaml.MapSlice, 0, len(pathItem))
+		for _, item := range pathItem {
+			switch {
+			case item.Key == "parameters":
+				if len(sharedParams) > 0 {
+					convertedItem = append(convertedItem, yaml.MapItem{Key: "parameters", Value: convertParameters(sharedParams)})
+				}
+			case isOperationKey(item.Key):
+				operation, _ := item.Value.(yaml.MapSlice)
+				convertedItem = append(convertedItem, yaml.MapItem{Key: item.Key, Value: c.convertOperation(operation, bodyParams)})
+			default:
+				convertedItem = append(convertedItem, item)
+			}
+		}
+		converted = append(converted, yaml.MapItem{Key: pathEntry.Key, Value: convertedItem})
+	}
+	return converted
+}
+
+func (c *swaggerConverter) convertOperation(operation yaml.MapSlice, inheritedBodyParams []interface{}) yaml.MapSlice {
+	consumes := toStrings(getItem(operation, "consumes"))
+	if len(consumes) == 0 {
+		consumes = c.consumes
+	}
+	produces := toStrings(getItem(operation, "produces"))
+	if len(produces) == 0 {
+		produces = c.produces
+	}
+
+	opParams, _ := getItem(operation, "parameters").([]interface{})
+	var params []interface{}
+	bodyParams := append([]interface{}{}, inheritedBodyParams...)
+	for _, p := range opParams {
+		if c.isBodyOrForm(p) {
+			bodyParams = append(bodyParams, p)
+		} else {
+			params = append(params, p)
+		}
+	}
+
+	converted := make(yaml.MapSlice, 0, len(operation)+1)
+	for _, item := range operation {
+		switch item.Key {
+		case "consumes", "produces", "schemes":
+			continue
+		case "parameters":
+			if len(params) > 0 {
+				converted = append(converted, yaml.MapItem{Key: "parameters", Value: convertParameters(params)})
+			}
+		case "responses":
+			if requestBody := c.buildRequestBody(bodyParams, consumes); requestBody != nil {
+				converted = append(converted, yaml.MapItem{Key: "requestBody", Value: requestBody})
+			}
+			responses, _ := item.Value.(yaml.MapSlice)
+			convertedResponses := make(yaml.MapSlice, 0, len(responses))
+			for _, response := range responses {
+				convertedResponses = append(convertedResponses, yaml.MapItem{Key: response.Key, Value: c.convertResponse(response.Value, produces)})
+			}
+			converted = append(converted, yaml.MapItem{Key: "responses", Value: convertedResponses})
+		default:
+			converted = append(converted, item)
+		}
+	}
+	return converted
+}
+
+// buildRequestBody combines the body or form parameters of an operation into
+// an OpenAPI 3 request body.
+func (c *swaggerConverter) buildRequestBody(params []interface{}, consumes []string) yaml.MapSlice {
+	if len(params) == 0 {
+		return nil
+	}
+	var requestBody yaml.MapSlice
+	var formProperties yaml.MapSlice
+	var formRequired []interface{}
+	hasFile := false
+
+	for _, p := range params {
+		param := c.deref(p)
+		if getItem(param, "in") == "body" {
+			if description := getItem(param, "description"); description != nil {
+				requestBody = setItem(requestBody, "description", description)
+			}
+			requestBody = setItem(requestBody, "content", buildContent(consumes, convertSchema(getItem(param, "schema")), nil))
+			if required, _ := getItem(param, "required").(bool); required {
+				requestBody = setItem(requestBody, "required", true)
+			}
+			continue
+		}
+
+		property := extractSchema(param)
+		if getItem(property, "type") == "file" {
+			property = yaml.MapSlice{{Key: "type", Value: "string"}, {Key: "format", Value: "binary"}}
+			hasFile = true
+		}
+		if description := getItem(param, "description"); description != nil {
+			property = setItem(property, "description", description)
+		}
+		formProperties = append(formProperties, yaml.MapItem{Key: getItem(param, "name"), Value: property})
+		if required, _ := getItem(param, "required").(bool); required {
+			formRequired = append(formRequired, getItem(param, "name"))
+		}
+	}
+
+	if len(formProperties) > 0 {
+		schema := yaml.MapSlice{{Key: "type", Value: "object"}, {Key: "properties", Value: formProperties}}
+		if len(formRequired) > 0 {
+			schema = append(schema, yaml.MapItem{Key: "required", Value: formRequired})
+		}
+		var formTypes []string
+		for _, contentType := range consumes {
+			if contentType == "multipart/form-data" || contentType == "application/x-www-form-urlencoded" {
+				formTypes = append(formTypes, contentType)
+			}
+		}
+		if len(formTypes) == 0 {
+			if hasFile {
+				formTypes = []string{"multipart/form-data"}
+			} else {
+				formTypes = []string{"application/x-www-form-urlencoded"}
+			}
+		}
+		requestBody = setItem(requestBody, "content", buildContent(formTypes, schema, nil))
+		if len(formRequired) > 0 {
+			requestBody = setItem(requestBody, "required", true)
+		}
+	}
+	return requestBody
+}
+
+func (c *swaggerConverter) convertResponse(node interface{}, produces []string) interface{} {
+	response, ok := node.(yaml.MapSlice)
+	if !ok || getItem(response, "$ref") != nil {
+		return node
+	}
+	converted := yaml.MapSlice{{Key: "description", Value: ""}}
+	examples, _ := getItem(response, "examples").(yaml.MapSlice)
+	for _, item := range response {
+		switch item.Key {
+		case "examples":
+			continue
+		case "schema":
+			converted = append(converted, yaml.MapItem{Key: "content", Value: buildContent(produces, convertSchema(item.Value), examples)})
+		case "headers":
+			headers, _ := item.Value.(yaml.MapSlice)
+			convertedHeaders := make(yaml.MapSlice, 0, len(headers))
+			for _, header := range headers {
+				h, _ := header.Value.(yaml.MapSlice)
+				convertedHeader := yaml.MapSlice{}
+				if description := getItem(h, "description"); description != nil {
+					convertedHeader = append(convertedHeader, yaml.MapItem{Key: "description", Value: description})
+				}
+				convertedHeader = append(convertedHeader, yaml.MapItem{Key: "schema", Value: extractSchema(h)})
+				convertedHeaders = append(convertedHeaders, yaml.MapItem{Key: header.Key, Value: convertedHeader})
+			}
+			converted = append(converted, yaml.MapItem{Key: "headers", Value: convertedHeaders})
+		default:
+			converted = setItem(converted, fmt.Sprint(item.Key), item.Value)
+		}
+	}
+	return converted
+}
+
+// convertGlobalParameters converts the spec's reusable parameters. Body and
+// form parameters have no equivalent, so are inlined where they are used.
+func (c *swaggerConverter) convertGlobalParameters(node interface{}) yaml.MapSlice {
+	params, _ := node.(yaml.MapSlice)
+	converted := yaml.MapSlice{}
+	for _, item := range params {
+		if !c.isBodyOrForm(item.Value) {
+			converted = append(converted, yaml.MapItem{Key: item.Key, Value: convertParameter(item.Value)})
+		}
+	}
+	return converted
+}
+
+func (c *swaggerConverter) deref(node interface{}) yaml.MapSlice {
+	param, _ := node.(yaml.MapSlice)
+	if ref, ok := getItem(param, "$ref").(string); ok {
+		if target, found := c.globalRef[ref]; found {
+			return target
+		}
+	}
+	return param
+}
+
+func (c *swaggerConverter) isBodyOrForm(node interface{}) bool {
+	in := getItem(c.deref(node), "in")
+	return in == "body" || in == "formData"
+}
+
+func convertParameters(params []interface{}) []interface{} {
+	converted := make([]interface{}, 0, len(params))
+	for _, p := range params {
+		converted = append(converted, convertParameter(p))
+	}
+	return converted
+}
+
+func convertParameter(node interface{}) interface{} {
+	param, ok := node.(yaml.MapSlice)
+	if !ok || getItem(param, "$ref") != nil {
+		return node
+	}
+	converted := yaml.MapSlice{}
+	for _, item := range param {
+		key := fmt.Sprint(item.Key)
+		if parameterSchemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "collectionFormat":
+			switch item.Value {
+			case "multi":
+				converted = append(converted, yaml.MapItem{Key: "explode", Value: true})
+			case "ssv":
+				converted = append(converted, yaml.MapItem{Key: "style", Value: "spaceDelimited"})
+			case "pipes":
+				converted = append(converted, yaml.MapItem{Key: "style", Value: "pipeDelimited"})
+			default:
+				converted = append(converted, yaml.MapItem{Key: "explode", Value: false})
+			}
+		default:
+			converted = append(converted, item)
+		}
+	}
+	return append(converted, yaml.MapItem{Key: "schema", Value: extractSchema(param)})
+}
+
+// extractSchema builds a schema from the keys of a Swagger 2.0
+// parameter or header that describe its value.
+func extractSchema(node yaml.MapSlice) yaml.MapSlice {
+	schema := yaml.MapSlice{}
+	for _, item := range node {
+		if parameterSchemaKeys[fmt.Sprint(item.Key)] {
+			schema = append(schema, item)
+		}
+	}
+	return schema
+}
+
+// convertSchema converts the Swagger 2.0 specific keywords in a schema.
+func convertSchema(node interface{}) interface{} {
+	switch n := node.(type) {
+	case yaml.MapSlice:
+		converted := make(yaml.MapSlice, 0, len(n))
+		for _, item := range n {
+			switch item.Key {
+			case "x-nullable":
+				converted = append(converted, yaml.MapItem{Key: "nullable", Value: item.Value})
+			case "discriminator":
+				if propertyName, ok := item.Value.(string); ok {
+					converted = append(converted, yaml.MapItem{Key: "discriminator", Value: yaml.MapSlice{{Key: "propertyName", Value: propertyName}}})
+				} else {
+					converted = append(converted, yaml.MapItem{Key: item.Key, Value: convertSchema(item.Value)})
+				}
+			case "type":
+				if item.Value == "file" {
+					converted = append(converted, yaml.MapItem{Key: "type", Value: "string"}, yaml.MapItem{Key: "format", Value: "binary"})
+				} else {
+					converted = append(converted, yaml.MapItem{Key: item.Key, Value: convertSchema(item.Value)})
+				}
+			default:
+				converted = append(converted, yaml.MapItem{Key: item.Key, Value: convertSchema(item.Value)})
+			}
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, 0, len(n))
+		for _, v := range n {
+			converted = append(converted, convertSchema(v))
+		}
+		return converted
+	default:
+		return node
+	}
+}
+
+func convertSecurityDefinitions(node interface{}) yaml.MapSlice {
+	definitions, _ := node.(yaml.MapSlice)
+	converted := make(yaml.MapSlice, 0, len(definitions))
+	for _, item := range definitions {
+		definition, _ := item.Value.(yaml.MapSlice)
+		var scheme yaml.MapSlice
+		switch getItem(definition, "type") {
+		case "basic":
+			scheme = yaml.MapSlice{{Key: "type", Value: "http"}, {Key: "scheme", Value: "basic"}}
+		case "oauth2":
+			flow := yaml.MapSlice{}
+			for _, key := range []string{"authorizationUrl", "tokenUrl", "scopes"} {
+				if value := getItem(definition, key); value != nil {
+					flow = append(flow, yaml.MapItem{Key: key, Value: value})
+				}
+			}
+			if getItem(flow, "scopes") == nil {
+				flow = append(flow, yaml.MapItem{Key: "scopes", Value: yaml.MapSlice{}})
+			}
+			flowName := oauth2Flows[fmt.Sprint(getItem(definition, "flow"))]
+			scheme = yaml.MapSlice{{Key: "type", Value: "oauth2"}, {Key: "flows", Value: yaml.MapSlice{{Key: flowName, Value: flow}}}}
+		default:
+			for _, key := range []string{"type", "name", "in"} {
+				if value := getItem(definition, key); value != nil {
+					scheme = append(scheme, yaml.MapItem{Key: key, Value: value})
+				}
+			}
+		}
+		if description := getItem(definition, "description"); description != nil {
+			scheme = append(scheme, yaml.MapItem{Key: "description", Value: description})
+		}
+		converted = append(converted, yaml.MapItem{Key: item.Key, Value: scheme})
+	}
+	return converted
+}
+
+func buildContent(contentTypes []string, schema interface{}, examples yaml.MapSlice) yaml.MapSlice {
+	content := make(yaml.MapSlice, 0, len(contentTypes))
+	for _, contentType := range contentTypes {
+		mediaType := yaml.MapSlice{{Key: "schema", Value: schema}}
+		if example := getItem(examples, contentType); example != nil {
+			mediaType = append(mediaType, yaml.MapItem{Key: "example", Value: example})
+		}
+		content = append(content, yaml.MapItem{Key: contentType, Value: mediaType})
+	}
+	return content
+}
+
+// mergeComponents adds the entries of value to the named section of components.
+func mergeComponents(components yaml.MapSlice, section string, value interface{}) yaml.MapSlice {
+	existing, _ := getItem(components, section).(yaml.MapSlice)
+	additions, _ := value.(yaml.MapSlice)
+	for _, item := range additions {
+		existing = setItem(existing, fmt.Sprint(item.Key), item.Value)
+	}
+	return setItem(components, section, existing)
+}
+
+// rewriteSwaggerRefs points references to Swagger 2.0 definitions, parameters
+// and responses at their OpenAPI 3 components.
+func rewriteSwaggerRefs(node interface{}) interface{} {
+	switch n := node.(type) {
+	case yaml.MapSlice:
+		for i, item := range n {
+			if ref, ok := item.Value.(string); ok && item.Key == "$ref" {
+				for from, to := range map[string]string{
+					"#/definitions/": "#/components/schemas/",
+					"#/parameters/":  "#/components/parameters/",
+					"#/responses/":   "#/components/responses/",
+				} {
+					if strings.HasPrefix(ref, from) {
+						n[i].Value = to + strings.TrimPrefix(ref, from)
+					}
+				}
+			} else {
+				n[i].Value = rewriteSwaggerRefs(item.Value)
+			}
+		}
+		return n
+	case []interface{}:
+		for i, v := range n {
+			n[i] = rewriteSwaggerRefs(v)
+		}
+		return n
+	default:
+		return node
+	}
+}
+
+func isOperationKey(key interface{}) bool {
+	for _, op := range operationKeys {
+		if key == op {
+			return true
+		}
+	}
+	return false
+}
+
+func toStrings(node interface{}) []string {
+	items, _ := node.([]interface{})
+	var values []string
+	for _, item := range items {
+		values = append(values, fmt.Sprint(item))
+	}
+	return values
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openapi
+
+import (
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"testing"
+)
+
+func TestConvertSwagger2(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{
+			name: "servers, parameters, body and definitions",
+			spec: `swagger: "2.0"
+info: {title: Pets, version: 1.0.0}
+host: pets.example.com
+basePath: /v1
+schemes: [http, https]
+paths:
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, type: string}
+    get:
+      parameters:
+        - {name: tags, in: query, type: array, items: {type: string}, collectionFormat: multi}
+      responses:
+        '200':
+          description: ok
+          schema: {$ref: '#/definitions/Pet'}
+    put:
+      parameters:
+        - {name: body, in: body, required: true, schema: {$ref: '#/definitions/Pet'}}
+      responses:
+        '204': {description: updated}
+definitions:
+  Pet: {type: object, properties: {name: {type: string}}}
+`,
+			want: `openapi: 3.0.3
+info: {title: Pets, version: 1.0.0}
+servers:
+  - url: http://pets.example.com/v1
+  - url: https://pets.example.com/v1
+paths:
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+    get:
+      parameters:
+        - {name: tags, in: query, explode: true, schema: {type: array, items: {type: string}}}
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+    put:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet'}
+        required: true
+      responses:
+        "204": {description: updated}
+components:
+  schemas:
+    Pet: {type: object, properties: {name: {type: string}}}
+`,
+		},
+		{
+			name: "no host, form data and basic auth",
+			spec: `swagger: "2.0"
+info: {title: Upload, version: 1.0.0}
+basePath: /api
+securityDefinitions:
+  basicAuth: {type: basic}
+paths:
+  /upload:
+    post:
+      consumes: [multipart/form-data]
+      parameters:
+        - {name: file, in: formData, type: file, required: true}
+        - {name: note, in: formData, type: string}
+      responses:
+        '201': {description: created}
+`,
+			want: `openapi: 3.0.3
+info: {title: Upload, version: 1.0.0}
+servers:
+  - url: /api
+paths:
+  /upload:
+    post:
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              properties:
+                file: {type: string, format: binary}
+                note: {type: string}
+              required: [file]
+        required: true
+      responses:
+        "201": {description: created}
+components:
+  securitySchemes:
+    basicAuth: {type: http, scheme: basic}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := ConvertSwagger2([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("ConvertSwagger2() error = %v", err)
+			}
+			var got, want interface{}
+			if err := yaml.Unmarshal(converted, &got); err != nil {
+				t.Fatalf("converted spec is not YAML: %v", err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ConvertSwagger2() =\n%s\nwant\n%s", converted, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertSwagger2RetainsJson(t *testing.T) {
+	converted, err := ConvertSwagger2([]byte(`{"swagger": "2.0", "info": {"title": "Pets", "version": "1"}, "paths": {}}`))
+	if err != nil {
+		t.Fatalf("ConvertSwagger2() error = %v", err)
+	}
+	want := `{"openapi":"3.0.3","info":{"title":"Pets","version":"1"},"servers":[{"url":"/"}],"paths":{}}`
+	if string(converted) != want {
+		t.Errorf("ConvertSwagger2() = %s, want %s", converted, want)
+	}
+}
+
+func TestBuildSwaggerServerUrls(t *testing.T) {
+	tests := []struct {
+		name     string
+		schemes  []string
+		host     string
+		basePath string
+		want     []string
+	}{
+		{name: "no host", basePath: "/v1", want: []string{"/v1"}},
+		{name: "no host or base path", want: []string{"/"}},
+		{name: "default scheme", host: "example.com", want: []string{"https://example.com"}},
+		{name: "each scheme", schemes: []string{"http", "https"}, host: "example.com", basePath: "/v1/", want: []string{"http://example.com/v1", "https://example.com/v1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildSwaggerServerUrls(tt.schemes, tt.host, tt.basePath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildSwaggerServerUrls() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sarif
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+const sarifVersion = "2.1.0"
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Rule is a rule that produced, or could have produced, results.
+type Rule struct {
+	Id          string
+	Description string
+
+	// Level is 'error', 'warning' or 'note'
+	Level string
+}
+
+// Result is a problem found by a rule.
+type Result struct {
+	RuleId  string
+	Level   string
+	Message string
+
+	// File is the path of the file the result is in, if not that of the log
+	File string
+
+	// Line and Column are zero if the result has no position in the file
+	Line   int
+	Column int
+
+	// Suppressed is true if the result is suppressed in the source file
+	Suppressed bool
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId       string             `json:"ruleId"`
+	RuleIndex    int                `json:"ruleIndex"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations,omitempty"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+type sarifSuppression struct {
+	Kind string `json:"kind"`
+}
+
+// Marshal returns a SARIF log of the results in the file, so they can be
+// shown by code review tools. The file's location is given relative to
+// baseDir, which should be the root of the repository. Results in another
+// file, given by their File, are located in that file instead, and results
+// with no file at all are not located. Suppressed results are marked as
+// suppressed in source.
+func Marshal(filePath string, baseDir string, rules []Rule, results []Result) ([]byte, error) {
+
+	driver := sarifDriver{
+		Name:           "opendeps",
+		InformationUri: "https://github.com/opendeps/cli",
+		Rules:          []sarifRule{},
+	}
+	ruleIndices := make(map[string]int)
+	for i, rule := range rules {
+		ruleIndices[rule.Id] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			Id:                   rule.Id,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: rule.Level},
+		})
+	}
+
+	sarifResults := []sarifResult{}
+	for _, result := range results {
+		resultFilePath := filePath
+		if result.File != "" {
+			resultFilePath = result.File
+		}
+		sarifResult := sarifResult{
+			RuleId:    result.RuleId,
+			RuleIndex: ruleIndices[result.RuleId],
+			Level:     result.Level,
+			Message:   sarifMessage{Text: result.Message},
+		}
+		if resultFilePath != "" {
+			location := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{Uri: toUri(resultFilePath, baseDir)},
+			}
+			if result.Line > 0 {
+				location.Region = &sarifRegion{StartLine: result.Line, StartColumn: result.Column}
+			}
+			sarifResult.Locations = []sarifLocation{{PhysicalLocation: location}}
+		}
+		if result.Suppressed {
+			sarifResult.Suppressions = []sarifSuppression{{Kind: "inSource"}}
+		}
+		sarifResults = append(sarifResults, sarifResult)
+	}
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: sarifResults}},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
+
+// toUri returns the location of the file relative to baseDir, if possible.
+func toUri(filePath string, baseDir string) string {
+	uri := filePath
+	if relative, err := filepath.Rel(baseDir, filePath); err == nil {
+		uri = relative
+	}
+	return filepath.ToSlash(uri)
+}
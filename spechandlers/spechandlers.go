@@ -0,0 +1,58 @@
+/*
+Copyright © 2021 Pete Cornish <outofcoffee@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spechandlers
+
+import (
+	"github.com/sirupsen/logrus"
+	"opendeps.org/opendeps/asyncapi"
+	"opendeps.org/opendeps/fileutil"
+	"opendeps.org/opendeps/graphqlspec"
+	"opendeps.org/opendeps/grpcspec"
+	"opendeps.org/opendeps/openapi"
+)
+
+// All returns the handler for each kind of spec, starting with
+// that for OpenAPI specs.
+func All() []openapi.SpecHandler {
+	return []openapi.SpecHandler{
+		openapi.Handler,
+		asyncapi.Handler,
+		grpcspec.Handler,
+		graphqlspec.Handler,
+	}
+}
+
+// Detect returns the handler for the spec at the location.
+func Detect(location string) openapi.SpecHandler {
+	raw, err := fileutil.ReadAllContent(location)
+	if err != nil {
+		logrus.Debugf("detecting kind of unreadable spec [%v] from its location: %v", location, err)
+		raw = nil
+	}
+	return DetectForContent(location, raw)
+}
+
+// DetectForContent returns the handler for the spec at the location, with
+// the given raw content. The OpenAPI handler is used for specs of no other kind.
+func DetectForContent(location string, raw []byte) openapi.SpecHandler {
+	for _, handler := range All()[1:] {
+		if handler.Detect(location, raw) {
+			return handler
+		}
+	}
+	return openapi.Handler
+}